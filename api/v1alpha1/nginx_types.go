@@ -7,18 +7,26 @@ package v1alpha1
 import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=ngx
 // +kubebuilder:subresource:status
 // +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.currentReplicas,selectorpath=.status.podSelector
 // +kubebuilder:printcolumn:name="Current",type=integer,JSONPath=`.status.currentReplicas`
 // +kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.spec.replicas`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Up-to-date",type=integer,JSONPath=`.status.updatedReplicas`
+// +kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // +kubebuilder:printcolumn:name="Ingress IPs",type=string,JSONPath=`.status.ingresses[*].ips[*]`
 // +kubebuilder:printcolumn:name="Service IPs",type=string,JSONPath=`.status.services[*].ips[*]`
+// +kubebuilder:printcolumn:name="Service Hostnames",type=string,JSONPath=`.status.services[*].hostnames[*]`,priority=1
 
 // Nginx is the Schema for the nginxes API
 type Nginx struct {
@@ -41,17 +49,73 @@ type NginxList struct {
 // NginxSpec defines the desired state of Nginx
 type NginxSpec struct {
 	// Replicas is the number of desired pods. Defaults to the default deployment
-	// replicas value.
+	// replicas value. It's ignored when Autoscaling is set.
 	// +optional
 	Replicas *int32 `json:"replicas,omitempty"`
+	// Autoscaling configures a HorizontalPodAutoscaler to manage the number of
+	// nginx pods.
+	// +optional
+	Autoscaling *NginxAutoscaling `json:"autoscaling,omitempty"`
+	// PodDisruptionBudget configures a PodDisruptionBudget to limit the number
+	// of nginx pods down simultaneously due to voluntary disruptions.
+	// +optional
+	PodDisruptionBudget *NginxPodDisruptionBudget `json:"podDisruptionBudget,omitempty"`
+	// Logging configures how the nginx logs are shipped.
+	// +optional
+	Logging *NginxLogging `json:"logging,omitempty"`
+	// Metrics configures a Prometheus exporter sidecar for the nginx pods.
+	// +optional
+	Metrics *NginxMetrics `json:"metrics,omitempty"`
+	// NetworkPolicy configures a NetworkPolicy which only allows traffic to
+	// the nginx ports and from the nginx pods to the given destinations.
+	// +optional
+	NetworkPolicy *NginxNetworkPolicy `json:"networkPolicy,omitempty"`
+	// DeploymentMode defines the workload running the nginx pods. Can be
+	// "Deployment" or "DaemonSet", the latter runs a pod on every node
+	// selected by podTemplate (e.g. nodeSelector and affinity) and doesn't
+	// support replicas, autoscaling, strategy nor validateConfig. Defaults to
+	// "Deployment".
+	// +kubebuilder:validation:Enum=Deployment;DaemonSet
+	// +optional
+	DeploymentMode DeploymentMode `json:"deploymentMode,omitempty"`
+	// Strategy defines how the nginx pods are replaced by new ones. Takes
+	// precedence over podTemplate.rollingUpdate.
+	// +optional
+	Strategy *NginxStrategy `json:"strategy,omitempty"`
+	// DriftPolicy defines what happens to manual changes made on the
+	// Deployment and Services managed by the operator. Can be "Enforce",
+	// which reverts them on the next reconcile, or "Tolerate", which keeps
+	// them until the Nginx spec changes (or a referenced ConfigMap or Secret
+	// rolls out the pods). Defaults to "Enforce".
+	// +kubebuilder:validation:Enum=Enforce;Tolerate
+	// +optional
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+	// DeletionPolicy defines what happens to the resources created for the
+	// Nginx when it's deleted.
+	// +optional
+	DeletionPolicy *NginxDeletionPolicy `json:"deletionPolicy,omitempty"`
 	// Image is the container image name. Defaults to "nginx:latest".
 	// +optional
 	Image string `json:"image,omitempty"`
+	// ImagePullPolicy defines the policy to pull the container image. Defaults
+	// to the default container imagePullPolicy value.
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// ImagePullSecrets are references to Secrets, in the same namespace, used
+	// to pull the images of the nginx pods from private registries.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
 	// Config is a reference to the NGINX config object which stores the NGINX
 	// configuration file. When provided the file is mounted in NGINX container on
 	// "/etc/nginx/nginx.conf".
 	// +optional
 	Config *ConfigRef `json:"config,omitempty"`
+	// ValidateConfig enables checking the NGINX configuration (`nginx -t`) on
+	// a short-lived Job before rolling out pods with a changed configuration
+	// (config, TLS certificates or image). The rollout is held while the
+	// validation does not succeed.
+	// +optional
+	ValidateConfig bool `json:"validateConfig,omitempty"`
 	// TLS configuration.
 	// +optional
 	TLS []NginxTLS `json:"tls,omitempty"`
@@ -61,9 +125,19 @@ type NginxSpec struct {
 	// Service to expose the nginx pod
 	// +optional
 	Service *NginxService `json:"service,omitempty"`
+	// InternalService is an additional Service, named "<nginx name>-internal",
+	// to expose the nginx pods with a different type and annotations than
+	// Service, e.g. a private ClusterIP next to a public LoadBalancer.
+	// +optional
+	InternalService *NginxService `json:"internalService,omitempty"`
 	// Ingress defines a convenient way to expose the Nginx service.
 	// +optional
 	Ingress *NginxIngress `json:"ingress,omitempty"`
+	// Gateway defines a convenient way to expose the Nginx service through a
+	// Gateway API HTTPRoute. It requires the Gateway API support to be enabled
+	// on the operator.
+	// +optional
+	Gateway *NginxGateway `json:"gateway,omitempty"`
 	// ExtraFiles references to additional files into a object in the cluster.
 	// These additional files will be mounted on `/etc/nginx/extra_files`.
 	// +optional
@@ -72,6 +146,11 @@ type NginxSpec struct {
 	// working or not.
 	// +optional
 	HealthcheckPath string `json:"healthcheckPath,omitempty"`
+	// Healthcheck customizes the probes of the nginx container. When set, the
+	// readiness probe (and the liveness one, if enabled) performs an HTTP
+	// request against the given path and port.
+	// +optional
+	Healthcheck *NginxHealthcheck `json:"healthcheck,omitempty"`
 	// Resources requirements to be set on the NGINX container.
 	// +optional
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
@@ -82,12 +161,232 @@ type NginxSpec struct {
 	// some event happens to nginx container.
 	// +optional
 	Lifecycle *NginxLifecycle `json:"lifecycle,omitempty"`
+	// ServiceAccount configures a ServiceAccount, named after the Nginx, to be
+	// managed by the operator and used by the nginx pods.
+	//
+	// It's mutually exclusive with PodTemplate.ServiceAccountName field.
+	// +optional
+	ServiceAccount *NginxServiceAccount `json:"serviceAccount,omitempty"`
+}
+
+type NginxServiceAccount struct {
+	// Annotations are extra annotations for the ServiceAccount, e.g. to bind a
+	// cloud provider identity (IRSA, Workload Identity).
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Labels are extra labels for the ServiceAccount.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// ImagePullSecrets are references to Secrets used to pull the images of
+	// pods using the ServiceAccount.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+type NginxHealthcheck struct {
+	// Path is the HTTP path requested by the probes. Defaults to
+	// HealthcheckPath, or "/" when it's empty.
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Port is the name or number of the container port requested by the
+	// probes. Defaults to "http".
+	// +optional
+	Port *intstr.IntOrString `json:"port,omitempty"`
+	// Scheme is the scheme used to connect to the port. Defaults to "HTTP".
+	// +optional
+	Scheme corev1.URIScheme `json:"scheme,omitempty"`
+	// Readiness configures the readiness probe thresholds.
+	// +optional
+	Readiness *NginxProbe `json:"readiness,omitempty"`
+	// Liveness enables the liveness probe with the given thresholds.
+	// +optional
+	Liveness *NginxProbe `json:"liveness,omitempty"`
+	// Startup enables the startup probe with the given thresholds. Useful when
+	// nginx takes long to load its configuration, since the other probes only
+	// start once the startup probe succeeds.
+	// +optional
+	Startup *NginxProbe `json:"startup,omitempty"`
+}
+
+// NginxProbe holds the timing and threshold settings of a probe. Zero values
+// fall back to the default probe values.
+type NginxProbe struct {
+	// InitialDelaySeconds is the number of seconds after the container has
+	// started before the probe is initiated.
+	// +optional
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+	// TimeoutSeconds is the number of seconds after which the probe times out.
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+	// PeriodSeconds is how often (in seconds) to perform the probe.
+	// +optional
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+	// SuccessThreshold is the minimum consecutive successes for the probe to
+	// be considered successful after having failed. Must be 1 on liveness
+	// and startup probes.
+	// +optional
+	SuccessThreshold int32 `json:"successThreshold,omitempty"`
+	// FailureThreshold is the minimum consecutive failures for the probe to be
+	// considered failed after having succeeded.
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+type NginxAutoscaling struct {
+	// MinReplicas is the lower limit for the number of pods. Defaults to 1.
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// MaxReplicas is the upper limit for the number of pods.
+	MaxReplicas int32 `json:"maxReplicas"`
+	// TargetCPUUtilizationPercentage is the target average CPU utilization
+	// (relative to the requested CPU) over all the pods. Defaults to 80% when
+	// no target is set.
+	// +optional
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+	// TargetMemoryUtilizationPercentage is the target average memory
+	// utilization (relative to the requested memory) over all the pods.
+	// +optional
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+}
+
+type NginxLogging struct {
+	// Sidecar injects a log shipping container which reads the log files
+	// written by nginx, for clusters where container stdout isn't collected.
+	// +optional
+	Sidecar *NginxLoggingSidecar `json:"sidecar,omitempty"`
+}
+
+type NginxLoggingSidecar struct {
+	// Image is the log shipper container image. Defaults to
+	// "fluent/fluent-bit:1.9".
+	// +optional
+	Image string `json:"image,omitempty"`
+	// LogsPath is the directory where nginx writes its log files. It's
+	// backed by an emptyDir volume shared with the sidecar, which mounts it
+	// at the same path. Defaults to "/var/log/nginx".
+	// +optional
+	LogsPath string `json:"logsPath,omitempty"`
+	// ConfigMapName is the name of the ConfigMap holding the log shipper
+	// configuration (e.g. its outputs).
+	ConfigMapName string `json:"configMapName"`
+	// ConfigPath is the directory where the ConfigMap is mounted in the
+	// sidecar. Defaults to "/fluent-bit/etc".
+	// +optional
+	ConfigPath string `json:"configPath,omitempty"`
+	// Resources are the compute resources required by the sidecar.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+type NginxMetrics struct {
+	// Enabled injects the nginx-prometheus-exporter sidecar and exposes its
+	// port, named "metrics", on the pods and on the Service.
+	Enabled bool `json:"enabled"`
+	// Image is the exporter container image. Defaults to
+	// "nginx/nginx-prometheus-exporter:0.11.0".
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Port is the port the exporter listens on. Defaults to 9113.
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// StubStatusPath is the path, served on the http port, where the nginx
+	// configuration exposes the stub_status module. Defaults to "/stub_status".
+	// +optional
+	StubStatusPath string `json:"stubStatusPath,omitempty"`
+	// Resources are the compute resources required by the exporter container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// ServiceMonitor customizes the Prometheus Operator ServiceMonitor created
+	// when metrics are enabled and its CRD is installed in the cluster.
+	// +optional
+	ServiceMonitor *NginxServiceMonitor `json:"serviceMonitor,omitempty"`
+}
+
+type NginxServiceMonitor struct {
+	// Interval at which metrics are scraped, e.g. "30s". Defaults to the
+	// Prometheus scrape interval.
+	// +optional
+	Interval string `json:"interval,omitempty"`
+	// Labels are extra labels added to the ServiceMonitor, usually to match
+	// the serviceMonitorSelector of the Prometheus instance.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type NginxNetworkPolicy struct {
+	// From lists the sources allowed to reach the nginx ports. When empty,
+	// the nginx ports can be reached from any source.
+	// +optional
+	From []networkingv1.NetworkPolicyPeer `json:"from,omitempty"`
+	// Egress lists the destinations (e.g. upstreams) the nginx pods are
+	// allowed to connect to. DNS traffic is always allowed.
+	// +optional
+	Egress []networkingv1.NetworkPolicyEgressRule `json:"egress,omitempty"`
+}
+
+type NginxDeletionPolicy struct {
+	// Service defines what happens to the Service, and to the internal one
+	// when enabled, when the Nginx is deleted. Can be "Delete" or "Orphan",
+	// the latter leaves the Services behind (e.g. to retain their load
+	// balancer IPs). Defaults to "Delete".
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +optional
+	Service DeletionPolicy `json:"service,omitempty"`
+}
+
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the resource along with the Nginx.
+	DeletionPolicyDelete = DeletionPolicy("Delete")
+	// DeletionPolicyOrphan removes the Nginx owner reference from the resource
+	// so it's left behind when the Nginx is deleted.
+	DeletionPolicyOrphan = DeletionPolicy("Orphan")
+)
+
+type NginxStrategy struct {
+	// Type of the deployment strategy. Can be "RollingUpdate" or "Recreate".
+	// Defaults to "RollingUpdate".
+	// +kubebuilder:validation:Enum=RollingUpdate;Recreate
+	// +optional
+	Type appsv1.DeploymentStrategyType `json:"type,omitempty"`
+	// RollingUpdate defines maxSurge and maxUnavailable of the rolling update.
+	// Only allowed when type is "RollingUpdate".
+	// +optional
+	RollingUpdate *appsv1.RollingUpdateDeployment `json:"rollingUpdate,omitempty"`
+	// SurgeOnUpgrade makes the pods of a new nginx major or minor version
+	// (according to the image tag) surge to the full capacity before the old
+	// ones are terminated, i.e. a rolling update with maxSurge 100% and
+	// maxUnavailable 0 until the upgrade finishes. Only allowed when type is
+	// "RollingUpdate".
+	// +optional
+	SurgeOnUpgrade bool `json:"surgeOnUpgrade,omitempty"`
+}
+
+type NginxPodDisruptionBudget struct {
+	// MinAvailable is the number (or percentage) of pods which must still be
+	// available after an eviction.
+	//
+	// It's mutually exclusive with MaxUnavailable field.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// MaxUnavailable is the number (or percentage) of pods which can be
+	// unavailable after an eviction.
+	//
+	// It's mutually exclusive with MinAvailable field.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
 }
 
 type NginxTLS struct {
 	// SecretName is the name of the Secret which contains the certificate-key
 	// pair. It must reside in the same Namespace as the Nginx resource.
 	//
+	// The certificate and key are mounted on the nginx container at
+	// "/etc/nginx/certs/<secretName>/tls.crt" and
+	// "/etc/nginx/certs/<secretName>/tls.key" respectively, so they can be
+	// referenced by the ssl_certificate and ssl_certificate_key directives.
+	//
 	// NOTE: The Secret should follow the Kubernetes TLS secrets type.
 	// More info: https://kubernetes.io/docs/concepts/configuration/secret/#tls-secrets.
 	SecretName string `json:"secretName"`
@@ -95,6 +394,21 @@ type NginxTLS struct {
 	// wildcard of hosts: "*".
 	// +optional
 	Hosts []string `json:"hosts,omitempty"`
+	// ClientAuth configures the CA bundle used to verify client certificates
+	// (mutual TLS).
+	// +optional
+	ClientAuth *NginxTLSClientAuth `json:"clientAuth,omitempty"`
+}
+
+type NginxTLSClientAuth struct {
+	// CASecretName is the name of the Secret which contains the CA bundle,
+	// under the "ca.crt" key. It must reside in the same Namespace as the
+	// Nginx resource.
+	//
+	// The bundle is mounted on the nginx container at
+	// "/etc/nginx/client-ca/<caSecretName>/ca.crt", so it can be referenced
+	// by the ssl_client_certificate directive (along with ssl_verify_client).
+	CASecretName string `json:"caSecretName"`
 }
 
 type NginxIngress struct {
@@ -107,6 +421,42 @@ type NginxIngress struct {
 	// IngressClassName is the class to be set on Ingress.
 	// +optional
 	IngressClassName *string `json:"ingressClassName,omitempty"`
+	// Hosts are additional hostnames routed to the Nginx service, besides the
+	// ones declared on TLS certificates.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+	// Path is the path prefix routed to the Nginx service on every host.
+	// Defaults to "/".
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+type NginxGateway struct {
+	// ParentRefs are references to the Gateways which the HTTPRoute must be
+	// attached to.
+	ParentRefs []NginxGatewayParentRef `json:"parentRefs"`
+	// Hostnames are the hostnames which the HTTPRoute matches against the Host
+	// header of requests.
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+	// Annotations are extra annotations for the HTTPRoute resource.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Labels are extra labels for the HTTPRoute resource.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type NginxGatewayParentRef struct {
+	// Name is the name of the Gateway.
+	Name string `json:"name"`
+	// Namespace is the namespace of the Gateway. Defaults to the Nginx
+	// namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// SectionName is the name of a specific listener on the Gateway.
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
 }
 
 type NginxService struct {
@@ -116,6 +466,10 @@ type NginxService struct {
 	// LoadBalancerIP is an optional load balancer IP for the service.
 	// +optional
 	LoadBalancerIP string `json:"loadBalancerIP,omitempty"`
+	// LoadBalancerSourceRanges restricts the client IPs allowed to reach the
+	// load balancer, if supported by the cloud provider.
+	// +optional
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
 	// Labels are extra labels for the service.
 	// +optional
 	Labels map[string]string `json:"labels,omitempty"`
@@ -127,10 +481,42 @@ type NginxService struct {
 	// externalTrafficPolicy value.
 	// +optional
 	ExternalTrafficPolicy corev1.ServiceExternalTrafficPolicyType `json:"externalTrafficPolicy,omitempty"`
+	// IPFamilies lists the IP families (IPv4, IPv6) assigned to the service.
+	// Defaults to the cluster's primary IP family.
+	// +optional
+	IPFamilies []corev1.IPFamily `json:"ipFamilies,omitempty"`
+	// IPFamilyPolicy can be "SingleStack", "PreferDualStack" or
+	// "RequireDualStack". Defaults to "SingleStack".
+	// +optional
+	IPFamilyPolicy *corev1.IPFamilyPolicyType `json:"ipFamilyPolicy,omitempty"`
+	// SessionAffinity can be "ClientIP" or "None", the former makes requests
+	// from the same client IP be sent to the same pod. Defaults to "None".
+	// +optional
+	SessionAffinity corev1.ServiceAffinity `json:"sessionAffinity,omitempty"`
+	// SessionAffinityConfig contains the configurations of the ClientIP
+	// session affinity (e.g. its timeout).
+	// +optional
+	SessionAffinityConfig *corev1.SessionAffinityConfig `json:"sessionAffinityConfig,omitempty"`
 	// UsePodSelector defines whether Service should automatically map the
 	// endpoints using the pod's label selector. Defaults to true.
 	// +optional
 	UsePodSelector *bool `json:"usePodSelector,omitempty"`
+	// Ports overrides the Service port number of the named nginx ports
+	// (podTemplate.ports). By default "http" and "https" are served on 80
+	// and 443, and any other named port on its containerPort.
+	// +optional
+	Ports []NginxServicePort `json:"ports,omitempty"`
+	// Headless creates an additional headless Service (clusterIP: None),
+	// named "<nginx name>-headless", so clients can discover each nginx pod.
+	// +optional
+	Headless bool `json:"headless,omitempty"`
+}
+
+type NginxServicePort struct {
+	// Name of the nginx port (in podTemplate.ports) exposed by this port.
+	Name string `json:"name"`
+	// Port is the number of the Service port.
+	Port int32 `json:"port"`
 }
 
 // ConfigRef is a reference to a config object.
@@ -143,13 +529,61 @@ type ConfigRef struct {
 	// It's mutually exclusive with Value field.
 	// +optional
 	Name string `json:"name,omitempty"`
-	// Value is the raw Nginx configuration. Required when Kind is "Inline".
+	// Value is the raw Nginx configuration. Required when Kind is "Inline"
+	// or "Template".
 	//
 	// It's mutually exclusive with Name field.
 	// +optional
 	Value string `json:"value,omitempty"`
+	// Values are user-supplied values available as .Values to the config
+	// template. Only allowed when Kind is "Template".
+	// +optional
+	Values map[string]string `json:"values,omitempty"`
+	// ExtraFiles are additional config files, e.g. included by nginx.conf,
+	// projected from several ConfigMaps and Secrets into a single directory.
+	// Changes to any of them roll out the nginx pods.
+	// +optional
+	ExtraFiles *NginxConfigFiles `json:"extraFiles,omitempty"`
 }
 
+type NginxConfigFiles struct {
+	// MountPath is the directory holding the files. Defaults to
+	// "/etc/nginx/conf.d".
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+	// Sources are the ConfigMaps and Secrets (in the same namespace) whose
+	// keys are projected as files, optionally renamed with items.
+	Sources []NginxConfigFileSource `json:"sources"`
+}
+
+// NginxConfigFileSource is either a ConfigMap or a Secret.
+type NginxConfigFileSource struct {
+	// +optional
+	ConfigMap *corev1.ConfigMapProjection `json:"configMap,omitempty"`
+	// +optional
+	Secret *corev1.SecretProjection `json:"secret,omitempty"`
+}
+
+type DeploymentMode string
+
+const (
+	// DeploymentModeDeployment runs the nginx pods on a Deployment.
+	DeploymentModeDeployment = DeploymentMode("Deployment")
+	// DeploymentModeDaemonSet runs a nginx pod on every selected node using a
+	// DaemonSet.
+	DeploymentModeDaemonSet = DeploymentMode("DaemonSet")
+)
+
+type DriftPolicy string
+
+const (
+	// DriftPolicyEnforce reverts the manual changes on the managed resources.
+	DriftPolicyEnforce = DriftPolicy("Enforce")
+	// DriftPolicyTolerate keeps the manual changes on the managed resources
+	// until the Nginx spec changes.
+	DriftPolicyTolerate = DriftPolicy("Tolerate")
+)
+
 type ConfigKind string
 
 const (
@@ -158,6 +592,11 @@ const (
 	// ConfigKindInline is a kinda of configuration that is setup as a annotation on the Pod
 	// and is inject as a file on the container using the Downward API.
 	ConfigKindInline = ConfigKind("Inline")
+	// ConfigKindTemplate is a kind of configuration whose Value is a Go
+	// template (text/template), rendered by the operator with the Nginx name,
+	// namespace, labels and annotations, the values of podTemplate.env and
+	// the config Values. The result is set up like the Inline kind.
+	ConfigKindTemplate = ConfigKind("Template")
 )
 
 // FilesRef is a reference to arbitrary files stored into a ConfigMap in the
@@ -182,12 +621,26 @@ type NginxPodTemplateSpec struct {
 	// Annotations are custom annotations to be set into Pod.
 	// +optional
 	Annotations map[string]string `json:"annotations,omitempty"`
-	// Labels are custom labels to be added into Pod.
+	// Labels are custom labels to be added into Pod. The labels used to select
+	// the Nginx pods cannot be overridden.
 	// +optional
 	Labels map[string]string `json:"labels,omitempty"`
-	// HostNetwork enabled causes the pod to use the host's network namespace.
+	// HostNetwork enabled causes the pod to use the host's network namespace,
+	// binding nginx straight to the node ports (80 and 443 by default).
 	// +optional
 	HostNetwork bool `json:"hostNetwork,omitempty"`
+	// DNSPolicy is the DNS policy of the nginx pods. Defaults to
+	// "ClusterFirstWithHostNet" when hostNetwork is enabled, otherwise to the
+	// default pod dnsPolicy value.
+	// +optional
+	DNSPolicy corev1.DNSPolicy `json:"dnsPolicy,omitempty"`
+	// DNSConfig customizes the resolv.conf of the nginx pods (e.g. ndots,
+	// nameservers and search domains).
+	// +optional
+	DNSConfig *corev1.PodDNSConfig `json:"dnsConfig,omitempty"`
+	// HostAliases are entries added to the /etc/hosts file of the nginx pods.
+	// +optional
+	HostAliases []corev1.HostAlias `json:"hostAliases,omitempty"`
 	// Ports is the list of ports used by nginx.
 	// +optional
 	Ports []corev1.ContainerPort `json:"ports,omitempty"`
@@ -204,6 +657,25 @@ type NginxPodTemplateSpec struct {
 	// +optional
 	ContainerSecurityContext *corev1.SecurityContext `json:"containerSecurityContext,omitempty"`
 
+	// Command replaces the nginx executable started by the nginx container,
+	// e.g. a launcher script which ends up executing nginx. It still runs
+	// only after the config has been tested by the postStart hook.
+	// Defaults to ["nginx"].
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// Args are the arguments passed to Command, replacing the default ones
+	// (-g "daemon off;"). Note that nginx must keep running in the foreground,
+	// e.g. ["-g", "daemon off; worker_processes 8;"].
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// Env is the list of environment variables set on the nginx container.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// EnvFrom is the list of ConfigMaps and Secrets whose keys are set as
+	// environment variables on the nginx container.
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+
 	// Volumes that will attach to nginx instances
 	// +optional
 	Volumes []corev1.Volume `json:"volumes,omitempty"`
@@ -229,6 +701,28 @@ type NginxPodTemplateSpec struct {
 	// TopologySpreadConstraints describes how a group of pods ought to spread across topology domains.
 	// +optional
 	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// PriorityClassName is the name of the PriorityClass of the nginx pods.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// RuntimeClassName is the name of the RuntimeClass used to run the nginx
+	// pods, e.g. gVisor or Kata Containers.
+	// +optional
+	RuntimeClassName *string `json:"runtimeClassName,omitempty"`
+	// DefaultAntiAffinity sets a pod anti-affinity which spreads the nginx
+	// pods across nodes and zones.
+	//
+	// It's mutually exclusive with Affinity.PodAntiAffinity field.
+	// +optional
+	DefaultAntiAffinity *NginxDefaultAntiAffinity `json:"defaultAntiAffinity,omitempty"`
+}
+
+type NginxDefaultAntiAffinity struct {
+	// Required forbids two nginx pods from running on the same node, leaving
+	// the exceeding pods pending. Otherwise, the pods are only preferably
+	// scheduled on different nodes. The spread across zones is always
+	// preferred.
+	// +optional
+	Required bool `json:"required,omitempty"`
 }
 
 type NginxCacheSpec struct {
@@ -244,6 +738,20 @@ type NginxCacheSpec struct {
 type NginxLifecycle struct {
 	PostStart *NginxLifecycleHandler `json:"postStart,omitempty"`
 	PreStop   *NginxLifecycleHandler `json:"preStop,omitempty"`
+	// GracefulShutdown generates a preStop hook that waits for the given
+	// period and then gracefully stops nginx with "nginx -s quit", letting
+	// in-flight connections drain. Mutually exclusive with PreStop.
+	// Make sure podTemplate.terminationGracePeriodSeconds is long enough to
+	// fit both the sleep and the connection draining.
+	// +optional
+	GracefulShutdown *NginxGracefulShutdown `json:"gracefulShutdown,omitempty"`
+}
+
+type NginxGracefulShutdown struct {
+	// SleepSeconds is the time waited before stopping nginx, so endpoints
+	// have time to be removed from load balancers. Defaults to 0.
+	// +optional
+	SleepSeconds int32 `json:"sleepSeconds,omitempty"`
 }
 
 type NginxLifecycleHandler struct {
@@ -252,14 +760,57 @@ type NginxLifecycleHandler struct {
 
 // NginxStatus defines the observed state of Nginx
 type NginxStatus struct {
+	// ObservedGeneration is the most recent generation of the NGINX spec
+	// processed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 	// CurrentReplicas is the last observed number from the NGINX object.
 	CurrentReplicas int32 `json:"currentReplicas,omitempty"`
+	// ReadyReplicas is the last observed number of ready pods from the NGINX object.
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// UpdatedReplicas is the last observed number of pods running the desired
+	// pod template from the NGINX object.
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+	// DesiredReplicas is the last observed number of desired pods from the NGINX object.
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
 	// PodSelector is the NGINX's pod label selector.
 	PodSelector string `json:"podSelector,omitempty"`
 
 	Deployments []DeploymentStatus `json:"deployments,omitempty"`
 	Services    []ServiceStatus    `json:"services,omitempty"`
 	Ingresses   []IngressStatus    `json:"ingresses,omitempty"`
+	// Pods are the last observed nginx pods.
+	// +optional
+	Pods []PodStatus `json:"pods,omitempty"`
+
+	// Upgrade is the nginx version upgrade being surged, see
+	// spec.strategy.surgeOnUpgrade.
+	// +optional
+	Upgrade *NginxUpgradeStatus `json:"upgrade,omitempty"`
+
+	// Conditions represent the latest available observations of the NGINX's state.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+const (
+	// NginxConditionAvailable means the NGINX has the minimum number of
+	// available pods to serve traffic.
+	NginxConditionAvailable = "Available"
+	// NginxConditionProgressing means the NGINX pods are being rolled out.
+	NginxConditionProgressing = "Progressing"
+	// NginxConditionDegraded means the NGINX (or its pods) are failing
+	// somehow e.g. image pull failures, crash loops or reconcile errors.
+	NginxConditionDegraded = "Degraded"
+)
+
+type NginxUpgradeStatus struct {
+	// FromVersion is the nginx version being replaced.
+	FromVersion string `json:"fromVersion"`
+	// ToVersion is the nginx version being rolled out.
+	ToVersion string `json:"toVersion"`
 }
 
 type DeploymentStatus struct {
@@ -272,6 +823,42 @@ type ServiceStatus struct {
 	Name      string   `json:"name"`
 	IPs       []string `json:"ips,omitempty"`
 	Hostnames []string `json:"hostnames,omitempty"`
+	// ClusterIP is the IP address assigned to the Service within the cluster.
+	ClusterIP string `json:"clusterIP,omitempty"`
+	// NodePorts are the ports allocated on every node for the Service, if any.
+	NodePorts []ServiceNodePort `json:"nodePorts,omitempty"`
+}
+
+type PodStatus struct {
+	// Name is the name of the pod.
+	Name string `json:"name"`
+	// PodIP is the IP address allocated to the pod.
+	PodIP string `json:"podIP,omitempty"`
+	// HostIP is the IP address of the node the pod is running on.
+	HostIP string `json:"hostIP,omitempty"`
+	// NodeName is the name of the node the pod is scheduled on.
+	NodeName string `json:"nodeName,omitempty"`
+	// Ready is whether the pod is ready to serve traffic.
+	Ready bool `json:"ready"`
+	// StartTime is when the pod was acknowledged by the kubelet.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Image is the image run by the nginx container.
+	Image string `json:"image,omitempty"`
+	// ImageID is the image ID (including the digest) run by the nginx
+	// container, once it has started.
+	ImageID string `json:"imageID,omitempty"`
+	// Version is the nginx version according to the image tag, if any.
+	Version string `json:"version,omitempty"`
+}
+
+type ServiceNodePort struct {
+	// Name is the name of the Service port.
+	Name string `json:"name"`
+	// Port is the number of the Service port.
+	Port int32 `json:"port"`
+	// NodePort is the port allocated on every node for this Service port.
+	NodePort int32 `json:"nodePort"`
 }
 
 type IngressStatus struct {
@@ -0,0 +1,481 @@
+// Copyright 2020 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package v1alpha1
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"text/template"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+const (
+	// DefaultNginxImage is the container image used when none is set.
+	DefaultNginxImage = "nginx:latest"
+
+	// Default ports (and their names) exposed by the nginx container.
+	DefaultHTTPPort            = int32(8080)
+	DefaultHTTPHostNetworkPort = int32(80)
+	DefaultHTTPPortName        = "http"
+
+	DefaultHTTPSPort            = int32(8443)
+	DefaultHTTPSHostNetworkPort = int32(443)
+	DefaultHTTPSPortName        = "https"
+
+	// MetricsExporterContainerName is the name of the Prometheus exporter
+	// sidecar injected when metrics are enabled.
+	MetricsExporterContainerName = "nginx-prometheus-exporter"
+
+	// LogShipperContainerName is the name of the log shipping sidecar
+	// injected when logging.sidecar is set.
+	LogShipperContainerName = "log-shipper"
+)
+
+// SetupWebhookWithManager registers the Nginx admission webhooks into the
+// manager's webhook server.
+func (n *Nginx) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(n).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-nginx-tsuru-io-v1alpha1-nginx,mutating=true,failurePolicy=fail,sideEffects=None,groups=nginx.tsuru.io,resources=nginxes,verbs=create;update,versions=v1alpha1,name=mnginx.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &Nginx{}
+
+// Default implements webhook.Defaulter. Only the fields which must be stored
+// are defaulted, the image and ports are resolved by the operator on every
+// reconcile (see SetDefaults) so later changes on their defaults apply to the
+// existing Nginxes.
+func (n *Nginx) Default() {
+	// NOTE: replicas are defaulted only on creation, otherwise Nginxes that
+	// already exist without replicas would have their pods scaled down.
+	if n.CreationTimestamp.IsZero() && n.Spec.Replicas == nil && n.Spec.Autoscaling == nil && n.Spec.DeploymentMode != DeploymentModeDaemonSet {
+		n.Spec.Replicas = func(i int32) *int32 { return &i }(1)
+	}
+}
+
+// SetDefaults fills the unset fields of the Nginx spec with the defaults
+// used in the nginx pods (image and ports).
+func (s *NginxSpec) SetDefaults() {
+	if s.Image == "" {
+		s.Image = DefaultNginxImage
+	}
+
+	setDefaultPorts(&s.PodTemplate)
+}
+
+func setDefaultPorts(podSpec *NginxPodTemplateSpec) {
+	if !hasPort(podSpec.Ports, DefaultHTTPPortName) {
+		httpPort := DefaultHTTPPort
+		if podSpec.HostNetwork {
+			httpPort = DefaultHTTPHostNetworkPort
+		}
+		podSpec.Ports = append(podSpec.Ports, corev1.ContainerPort{
+			Name:          DefaultHTTPPortName,
+			ContainerPort: httpPort,
+			Protocol:      corev1.ProtocolTCP,
+		})
+	}
+
+	if !hasPort(podSpec.Ports, DefaultHTTPSPortName) {
+		httpsPort := DefaultHTTPSPort
+		if podSpec.HostNetwork {
+			httpsPort = DefaultHTTPSHostNetworkPort
+		}
+		podSpec.Ports = append(podSpec.Ports, corev1.ContainerPort{
+			Name:          DefaultHTTPSPortName,
+			ContainerPort: httpsPort,
+			Protocol:      corev1.ProtocolTCP,
+		})
+	}
+}
+
+func hasPort(ports []corev1.ContainerPort, name string) bool {
+	for _, port := range ports {
+		if port.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// +kubebuilder:webhook:path=/validate-nginx-tsuru-io-v1alpha1-nginx,mutating=false,failurePolicy=fail,sideEffects=None,groups=nginx.tsuru.io,resources=nginxes,verbs=create;update,versions=v1alpha1,name=vnginx.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Nginx{}
+
+// ValidateCreate implements webhook.Validator.
+func (n *Nginx) ValidateCreate() error {
+	return n.validate()
+}
+
+// ValidateUpdate implements webhook.Validator.
+func (n *Nginx) ValidateUpdate(old runtime.Object) error {
+	return n.validate()
+}
+
+// ValidateDelete implements webhook.Validator.
+func (n *Nginx) ValidateDelete() error {
+	return nil
+}
+
+func (n *Nginx) validate() error {
+	errs := validateNginxSpec(&n.Spec, field.NewPath("spec"))
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("Nginx").GroupKind(), n.Name, errs)
+}
+
+func validateNginxSpec(spec *NginxSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if spec.Replicas != nil && *spec.Replicas < 0 {
+		errs = append(errs, field.Invalid(path.Child("replicas"), *spec.Replicas, "must be greater than or equal to 0"))
+	}
+
+	if a := spec.Autoscaling; a != nil {
+		if a.MaxReplicas < 1 {
+			errs = append(errs, field.Invalid(path.Child("autoscaling", "maxReplicas"), a.MaxReplicas, "must be greater than or equal to 1"))
+		}
+
+		if a.MinReplicas != nil && (*a.MinReplicas < 1 || *a.MinReplicas > a.MaxReplicas) {
+			errs = append(errs, field.Invalid(path.Child("autoscaling", "minReplicas"), *a.MinReplicas, "must be between 1 and maxReplicas"))
+		}
+	}
+
+	if spec.DeploymentMode == DeploymentModeDaemonSet {
+		errs = append(errs, validateDaemonSetMode(spec, path)...)
+	}
+
+	if pdb := spec.PodDisruptionBudget; pdb != nil && pdb.MinAvailable != nil && pdb.MaxUnavailable != nil {
+		errs = append(errs, field.Forbidden(path.Child("podDisruptionBudget"), "minAvailable and maxUnavailable are mutually exclusive"))
+	}
+
+	if s := spec.Strategy; s != nil && s.Type == appsv1.RecreateDeploymentStrategyType && s.RollingUpdate != nil {
+		errs = append(errs, field.Forbidden(path.Child("strategy", "rollingUpdate"), "may not be set when type is Recreate"))
+	}
+
+	if s := spec.Strategy; s != nil && s.Type == appsv1.RecreateDeploymentStrategyType && s.SurgeOnUpgrade {
+		errs = append(errs, field.Forbidden(path.Child("strategy", "surgeOnUpgrade"), "may not be set when type is Recreate"))
+	}
+
+	if c := spec.Config; c != nil {
+		errs = append(errs, validateConfigRef(c, path.Child("config"))...)
+	}
+
+	for i, t := range spec.TLS {
+		for _, msg := range validation.IsDNS1123Subdomain(t.SecretName) {
+			errs = append(errs, field.Invalid(path.Child("tls").Index(i).Child("secretName"), t.SecretName, msg+" (the Secret must reside in the same namespace as the Nginx)"))
+		}
+
+		if t.ClientAuth != nil {
+			for _, msg := range validation.IsDNS1123Subdomain(t.ClientAuth.CASecretName) {
+				errs = append(errs, field.Invalid(path.Child("tls").Index(i).Child("clientAuth", "caSecretName"), t.ClientAuth.CASecretName, msg+" (the Secret must reside in the same namespace as the Nginx)"))
+			}
+		}
+	}
+
+	errs = append(errs, validatePorts(spec.PodTemplate.Ports, path.Child("podTemplate", "ports"))...)
+
+	if spec.PodTemplate.HostNetwork {
+		for i, port := range spec.PodTemplate.Ports {
+			if port.HostPort != 0 && port.HostPort != port.ContainerPort {
+				errs = append(errs, field.Invalid(path.Child("podTemplate", "ports").Index(i).Child("hostPort"), port.HostPort, "must match containerPort when hostNetwork is enabled"))
+			}
+		}
+	}
+
+	if spec.PodTemplate.DefaultAntiAffinity != nil && spec.PodTemplate.Affinity != nil && spec.PodTemplate.Affinity.PodAntiAffinity != nil {
+		errs = append(errs, field.Forbidden(path.Child("podTemplate", "defaultAntiAffinity"), "defaultAntiAffinity and affinity.podAntiAffinity are mutually exclusive"))
+	}
+
+	if spec.ServiceAccount != nil && spec.PodTemplate.ServiceAccountName != "" {
+		errs = append(errs, field.Forbidden(path.Child("serviceAccount"), "serviceAccount and podTemplate.serviceAccountName are mutually exclusive"))
+	}
+
+	if l := spec.Lifecycle; l != nil && l.GracefulShutdown != nil {
+		if l.PreStop != nil {
+			errs = append(errs, field.Forbidden(path.Child("lifecycle", "gracefulShutdown"), "gracefulShutdown and preStop are mutually exclusive"))
+		}
+
+		if l.GracefulShutdown.SleepSeconds < 0 {
+			errs = append(errs, field.Invalid(path.Child("lifecycle", "gracefulShutdown", "sleepSeconds"), l.GracefulShutdown.SleepSeconds, "must be greater than or equal to 0"))
+		}
+	}
+
+	if h := spec.Healthcheck; h != nil {
+		errs = append(errs, validateProbe(h.Readiness, false, path.Child("healthcheck", "readiness"))...)
+		errs = append(errs, validateProbe(h.Liveness, true, path.Child("healthcheck", "liveness"))...)
+		errs = append(errs, validateProbe(h.Startup, true, path.Child("healthcheck", "startup"))...)
+	}
+
+	metricsEnabled := spec.Metrics != nil && spec.Metrics.Enabled
+	logShipperEnabled := spec.Logging != nil && spec.Logging.Sidecar != nil
+	for i, c := range spec.PodTemplate.Containers {
+		if c.Name == "nginx" || (metricsEnabled && c.Name == MetricsExporterContainerName) || (logShipperEnabled && c.Name == LogShipperContainerName) {
+			errs = append(errs, field.Invalid(path.Child("podTemplate", "containers").Index(i).Child("name"), c.Name, "is reserved for the container managed by the operator"))
+		}
+	}
+
+	if logShipperEnabled && spec.Logging.Sidecar.ConfigMapName == "" {
+		errs = append(errs, field.Required(path.Child("logging", "sidecar", "configMapName"), ""))
+	}
+
+	if m := spec.Metrics; m != nil && m.Port != 0 {
+		for _, msg := range validation.IsValidPortNum(int(m.Port)) {
+			errs = append(errs, field.Invalid(path.Child("metrics", "port"), m.Port, msg))
+		}
+	}
+
+	if s := spec.Service; s != nil {
+		errs = append(errs, validateService(s, path.Child("service"))...)
+		errs = append(errs, validateServicePorts(s.Ports, spec.PodTemplate.Ports, path.Child("service", "ports"))...)
+	}
+
+	if s := spec.InternalService; s != nil {
+		errs = append(errs, validateService(s, path.Child("internalService"))...)
+		errs = append(errs, validateServicePorts(s.Ports, spec.PodTemplate.Ports, path.Child("internalService", "ports"))...)
+
+		if s.Headless {
+			errs = append(errs, field.Forbidden(path.Child("internalService", "headless"), "may only be set on service"))
+		}
+	}
+
+	return errs
+}
+
+// validateProbe rejects the probe settings the API server would refuse on the
+// nginx container, e.g. a success threshold other than 1 on liveness and
+// startup probes.
+func validateProbe(p *NginxProbe, singleSuccess bool, path *field.Path) field.ErrorList {
+	if p == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+	for _, f := range []struct {
+		name  string
+		value int32
+	}{
+		{"initialDelaySeconds", p.InitialDelaySeconds},
+		{"timeoutSeconds", p.TimeoutSeconds},
+		{"periodSeconds", p.PeriodSeconds},
+		{"successThreshold", p.SuccessThreshold},
+		{"failureThreshold", p.FailureThreshold},
+	} {
+		if f.value < 0 {
+			errs = append(errs, field.Invalid(path.Child(f.name), f.value, "must be greater than or equal to 0"))
+		}
+	}
+
+	if singleSuccess && p.SuccessThreshold > 1 {
+		errs = append(errs, field.Invalid(path.Child("successThreshold"), p.SuccessThreshold, "must be 1 on liveness and startup probes"))
+	}
+
+	return errs
+}
+
+func validateDaemonSetMode(spec *NginxSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	const msg = "may not be set when deploymentMode is DaemonSet"
+
+	if spec.Replicas != nil {
+		errs = append(errs, field.Forbidden(path.Child("replicas"), msg))
+	}
+
+	if spec.Autoscaling != nil {
+		errs = append(errs, field.Forbidden(path.Child("autoscaling"), msg))
+	}
+
+	if spec.Strategy != nil {
+		errs = append(errs, field.Forbidden(path.Child("strategy"), msg))
+	}
+
+	if spec.PodTemplate.RollingUpdate != nil {
+		errs = append(errs, field.Forbidden(path.Child("podTemplate", "rollingUpdate"), msg))
+	}
+
+	if spec.ValidateConfig {
+		errs = append(errs, field.Forbidden(path.Child("validateConfig"), msg))
+	}
+
+	return errs
+}
+
+func validateServicePorts(ports []NginxServicePort, containerPorts []corev1.ContainerPort, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	numbers := make(map[int32]bool)
+	for i, port := range ports {
+		p := path.Index(i)
+
+		if port.Name != DefaultHTTPPortName && port.Name != DefaultHTTPSPortName && !hasPort(containerPorts, port.Name) {
+			errs = append(errs, field.NotFound(p.Child("name"), port.Name))
+		}
+
+		for _, msg := range validation.IsValidPortNum(int(port.Port)) {
+			errs = append(errs, field.Invalid(p.Child("port"), port.Port, msg))
+		}
+
+		if numbers[port.Port] {
+			errs = append(errs, field.Duplicate(p.Child("port"), port.Port))
+		}
+		numbers[port.Port] = true
+	}
+
+	return errs
+}
+
+func validateConfigRef(c *ConfigRef, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	switch c.Kind {
+	case ConfigKindConfigMap:
+		if c.Name == "" {
+			errs = append(errs, field.Required(path.Child("name"), "required when kind is ConfigMap"))
+		}
+
+		if c.Value != "" {
+			errs = append(errs, field.Forbidden(path.Child("value"), "must not be set when kind is ConfigMap"))
+		}
+
+	case ConfigKindInline:
+		if c.Value == "" {
+			errs = append(errs, field.Required(path.Child("value"), "required when kind is Inline"))
+		}
+
+		if c.Name != "" {
+			errs = append(errs, field.Forbidden(path.Child("name"), "must not be set when kind is Inline"))
+		}
+
+	case ConfigKindTemplate:
+		if c.Value == "" {
+			errs = append(errs, field.Required(path.Child("value"), "required when kind is Template"))
+		}
+
+		if _, err := template.New("nginx.conf").Parse(c.Value); err != nil {
+			errs = append(errs, field.Invalid(path.Child("value"), c.Value, fmt.Sprintf("must be a valid Go template: %s", err)))
+		}
+
+		if c.Name != "" {
+			errs = append(errs, field.Forbidden(path.Child("name"), "must not be set when kind is Template"))
+		}
+
+	default:
+		errs = append(errs, field.NotSupported(path.Child("kind"), c.Kind, []string{string(ConfigKindConfigMap), string(ConfigKindInline), string(ConfigKindTemplate)}))
+	}
+
+	if f := c.ExtraFiles; f != nil {
+		if f.MountPath != "" && !strings.HasPrefix(f.MountPath, "/") {
+			errs = append(errs, field.Invalid(path.Child("extraFiles", "mountPath"), f.MountPath, "must be an absolute path"))
+		}
+
+		for i, src := range f.Sources {
+			if (src.ConfigMap == nil) == (src.Secret == nil) {
+				errs = append(errs, field.Invalid(path.Child("extraFiles", "sources").Index(i), "", "must set exactly one of configMap or secret"))
+			}
+		}
+	}
+
+	if len(c.Values) > 0 && c.Kind != ConfigKindTemplate {
+		errs = append(errs, field.Forbidden(path.Child("values"), "may only be set when kind is Template"))
+	}
+
+	return errs
+}
+
+func validatePorts(ports []corev1.ContainerPort, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	names := make(map[string]bool)
+	for i, port := range ports {
+		p := path.Index(i)
+
+		for _, msg := range validation.IsValidPortNum(int(port.ContainerPort)) {
+			errs = append(errs, field.Invalid(p.Child("containerPort"), port.ContainerPort, msg))
+		}
+
+		if port.HostPort != 0 {
+			for _, msg := range validation.IsValidPortNum(int(port.HostPort)) {
+				errs = append(errs, field.Invalid(p.Child("hostPort"), port.HostPort, msg))
+			}
+		}
+
+		if port.Name == "" {
+			continue
+		}
+
+		for _, msg := range validation.IsValidPortName(port.Name) {
+			errs = append(errs, field.Invalid(p.Child("name"), port.Name, msg))
+		}
+
+		if names[port.Name] {
+			errs = append(errs, field.Duplicate(p.Child("name"), port.Name))
+		}
+
+		names[port.Name] = true
+	}
+
+	return errs
+}
+
+func validateService(s *NginxService, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	switch s.Type {
+	case "", corev1.ServiceTypeClusterIP, corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("type"), s.Type, []string{
+			string(corev1.ServiceTypeClusterIP),
+			string(corev1.ServiceTypeNodePort),
+			string(corev1.ServiceTypeLoadBalancer),
+		}))
+	}
+
+	if s.LoadBalancerIP != "" && s.Type != corev1.ServiceTypeLoadBalancer {
+		errs = append(errs, field.Forbidden(path.Child("loadBalancerIP"), "may only be set when type is LoadBalancer"))
+	}
+
+	if len(s.LoadBalancerSourceRanges) > 0 && s.Type != corev1.ServiceTypeLoadBalancer {
+		errs = append(errs, field.Forbidden(path.Child("loadBalancerSourceRanges"), "may only be set when type is LoadBalancer"))
+	}
+
+	for i, cidr := range s.LoadBalancerSourceRanges {
+		if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+			errs = append(errs, field.Invalid(path.Child("loadBalancerSourceRanges").Index(i), cidr, "must be a valid CIDR, e.g. 10.0.0.0/8"))
+		}
+	}
+
+	if s.ExternalTrafficPolicy != "" && s.Type != corev1.ServiceTypeLoadBalancer && s.Type != corev1.ServiceTypeNodePort {
+		errs = append(errs, field.Forbidden(path.Child("externalTrafficPolicy"), "may only be set when type is NodePort or LoadBalancer"))
+	}
+
+	switch s.SessionAffinity {
+	case "", corev1.ServiceAffinityNone, corev1.ServiceAffinityClientIP:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("sessionAffinity"), s.SessionAffinity, []string{
+			string(corev1.ServiceAffinityNone),
+			string(corev1.ServiceAffinityClientIP),
+		}))
+	}
+
+	if s.SessionAffinityConfig != nil && s.SessionAffinity != corev1.ServiceAffinityClientIP {
+		errs = append(errs, field.Forbidden(path.Child("sessionAffinityConfig"), "may only be set when sessionAffinity is ClientIP"))
+	}
+
+	return errs
+}
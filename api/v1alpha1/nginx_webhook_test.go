@@ -0,0 +1,352 @@
+// Copyright 2020 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestNginx_Default(t *testing.T) {
+	tests := []struct {
+		name     string
+		nginx    Nginx
+		expected NginxSpec
+	}{
+		{
+			name: "new nginx",
+			expected: NginxSpec{
+				Replicas: func(n int32) *int32 { return &n }(1),
+			},
+		},
+		{
+			name: "existing nginx with host network and autoscaling",
+			nginx: Nginx{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Now()},
+				Spec: NginxSpec{
+					Image:       "tsuru/nginx:1.22",
+					Autoscaling: &NginxAutoscaling{MaxReplicas: 10},
+					PodTemplate: NginxPodTemplateSpec{
+						HostNetwork: true,
+						Ports:       []corev1.ContainerPort{{Name: "http", ContainerPort: 8000}},
+					},
+				},
+			},
+			expected: NginxSpec{
+				Image:       "tsuru/nginx:1.22",
+				Autoscaling: &NginxAutoscaling{MaxReplicas: 10},
+				PodTemplate: NginxPodTemplateSpec{
+					HostNetwork: true,
+					Ports:       []corev1.ContainerPort{{Name: "http", ContainerPort: 8000}},
+				},
+			},
+		},
+		{
+			name: "new nginx in daemonset mode",
+			nginx: Nginx{
+				Spec: NginxSpec{DeploymentMode: DeploymentModeDaemonSet},
+			},
+			expected: NginxSpec{
+				DeploymentMode: DeploymentModeDaemonSet,
+			},
+		},
+		{
+			name: "existing nginx without replicas",
+			nginx: Nginx{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Now()},
+			},
+			expected: NginxSpec{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.nginx.Default()
+			assert.Equal(t, tt.expected, tt.nginx.Spec)
+		})
+	}
+}
+
+func TestNginxSpec_SetDefaults(t *testing.T) {
+	spec := NginxSpec{
+		PodTemplate: NginxPodTemplateSpec{
+			HostNetwork: true,
+			Ports:       []corev1.ContainerPort{{Name: "http", ContainerPort: 8000}},
+		},
+	}
+
+	spec.SetDefaults()
+
+	assert.Equal(t, NginxSpec{
+		Image: "nginx:latest",
+		PodTemplate: NginxPodTemplateSpec{
+			HostNetwork: true,
+			Ports: []corev1.ContainerPort{
+				{Name: "http", ContainerPort: 8000},
+				{Name: "https", ContainerPort: 443, Protocol: corev1.ProtocolTCP},
+			},
+		},
+	}, spec)
+}
+
+func TestNginx_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          NginxSpec
+		expectedError string
+	}{
+		{
+			name: "empty spec",
+		},
+		{
+			name: "valid spec",
+			spec: NginxSpec{
+				Replicas: func(n int32) *int32 { return &n }(3),
+				Config:   &ConfigRef{Kind: ConfigKindConfigMap, Name: "my-config"},
+				TLS:      []NginxTLS{{SecretName: "my-cert"}},
+				PodTemplate: NginxPodTemplateSpec{
+					Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+				},
+				Service: &NginxService{
+					Type:                  corev1.ServiceTypeLoadBalancer,
+					LoadBalancerIP:        "10.0.0.1",
+					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+				},
+			},
+		},
+		{
+			name:          "negative replicas",
+			spec:          NginxSpec{Replicas: func(n int32) *int32 { return &n }(-1)},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.replicas: Invalid value: -1: must be greater than or equal to 0`,
+		},
+		{
+			name: "min replicas greater than max replicas",
+			spec: NginxSpec{
+				Autoscaling: &NginxAutoscaling{MinReplicas: func(n int32) *int32 { return &n }(5), MaxReplicas: 2},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.autoscaling.minReplicas: Invalid value: 5: must be between 1 and maxReplicas`,
+		},
+		{
+			name: "both min available and max unavailable",
+			spec: NginxSpec{
+				PodDisruptionBudget: &NginxPodDisruptionBudget{
+					MinAvailable:   func(v intstr.IntOrString) *intstr.IntOrString { return &v }(intstr.FromInt(1)),
+					MaxUnavailable: func(v intstr.IntOrString) *intstr.IntOrString { return &v }(intstr.FromInt(1)),
+				},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.podDisruptionBudget: Forbidden: minAvailable and maxUnavailable are mutually exclusive`,
+		},
+		{
+			name: "replicas and autoscaling on daemonset mode",
+			spec: NginxSpec{
+				DeploymentMode: DeploymentModeDaemonSet,
+				Replicas:       func(n int32) *int32 { return &n }(2),
+				Autoscaling:    &NginxAutoscaling{MaxReplicas: 10},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: [spec.replicas: Forbidden: may not be set when deploymentMode is DaemonSet, spec.autoscaling: Forbidden: may not be set when deploymentMode is DaemonSet]`,
+		},
+		{
+			name: "rolling update params on recreate strategy",
+			spec: NginxSpec{
+				Strategy: &NginxStrategy{Type: appsv1.RecreateDeploymentStrategyType, RollingUpdate: &appsv1.RollingUpdateDeployment{}},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.strategy.rollingUpdate: Forbidden: may not be set when type is Recreate`,
+		},
+		{
+			name: "surge on upgrade on recreate strategy",
+			spec: NginxSpec{
+				Strategy: &NginxStrategy{Type: appsv1.RecreateDeploymentStrategyType, SurgeOnUpgrade: true},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.strategy.surgeOnUpgrade: Forbidden: may not be set when type is Recreate`,
+		},
+		{
+			name:          "inline config without value",
+			spec:          NginxSpec{Config: &ConfigRef{Kind: ConfigKindInline}},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.config.value: Required value: required when kind is Inline`,
+		},
+		{
+			name:          "malformed config template",
+			spec:          NginxSpec{Config: &ConfigRef{Kind: ConfigKindTemplate, Value: "{{ .Name"}},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.config.value: Invalid value: "{{ .Name": must be a valid Go template: template: nginx.conf:1: unclosed action`,
+		},
+		{
+			name:          "config values on inline config",
+			spec:          NginxSpec{Config: &ConfigRef{Kind: ConfigKindInline, Value: "events {}", Values: map[string]string{"foo": "bar"}}},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.config.values: Forbidden: may only be set when kind is Template`,
+		},
+		{
+			name: "config file source with both configmap and secret",
+			spec: NginxSpec{Config: &ConfigRef{
+				Kind: ConfigKindConfigMap,
+				Name: "my-config",
+				ExtraFiles: &NginxConfigFiles{Sources: []NginxConfigFileSource{
+					{ConfigMap: &corev1.ConfigMapProjection{}, Secret: &corev1.SecretProjection{}},
+				}},
+			}},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.config.extraFiles.sources[0]: Invalid value: "": must set exactly one of configMap or secret`,
+		},
+		{
+			name:          "TLS secret from another namespace",
+			spec:          NginxSpec{TLS: []NginxTLS{{SecretName: "other-namespace/my-cert"}}},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.tls[0].secretName: Invalid value: "other-namespace/my-cert": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*') (the Secret must reside in the same namespace as the Nginx)`,
+		},
+		{
+			name: "malformed ports",
+			spec: NginxSpec{
+				PodTemplate: NginxPodTemplateSpec{
+					Ports: []corev1.ContainerPort{
+						{Name: "http", ContainerPort: 70000},
+						{Name: "http", ContainerPort: 8080},
+					},
+				},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: [spec.podTemplate.ports[0].containerPort: Invalid value: 70000: must be between 1 and 65535, inclusive, spec.podTemplate.ports[1].name: Duplicate value: "http"]`,
+		},
+		{
+			name: "host port different from container port on host network",
+			spec: NginxSpec{
+				PodTemplate: NginxPodTemplateSpec{
+					HostNetwork: true,
+					Ports:       []corev1.ContainerPort{{Name: "http", ContainerPort: 80, HostPort: 8080}},
+				},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.podTemplate.ports[0].hostPort: Invalid value: 8080: must match containerPort when hostNetwork is enabled`,
+		},
+		{
+			name: "default anti-affinity and pod anti-affinity",
+			spec: NginxSpec{
+				PodTemplate: NginxPodTemplateSpec{
+					DefaultAntiAffinity: &NginxDefaultAntiAffinity{},
+					Affinity:            &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{}},
+				},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.podTemplate.defaultAntiAffinity: Forbidden: defaultAntiAffinity and affinity.podAntiAffinity are mutually exclusive`,
+		},
+		{
+			name: "sidecar container named as the nginx container",
+			spec: NginxSpec{
+				PodTemplate: NginxPodTemplateSpec{
+					Containers: []corev1.Container{{Name: "nginx", Image: "nginx:alpine"}},
+				},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.podTemplate.containers[0].name: Invalid value: "nginx": is reserved for the container managed by the operator`,
+		},
+		{
+			name: "sidecar container named as the metrics exporter container",
+			spec: NginxSpec{
+				Metrics: &NginxMetrics{Enabled: true},
+				PodTemplate: NginxPodTemplateSpec{
+					Containers: []corev1.Container{{Name: "nginx-prometheus-exporter", Image: "my-exporter"}},
+				},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.podTemplate.containers[0].name: Invalid value: "nginx-prometheus-exporter": is reserved for the container managed by the operator`,
+		},
+		{
+			name: "log shipper sidecar without config",
+			spec: NginxSpec{
+				Logging: &NginxLogging{Sidecar: &NginxLoggingSidecar{}},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.logging.sidecar.configMapName: Required value`,
+		},
+		{
+			name: "managed service account and service account name",
+			spec: NginxSpec{
+				ServiceAccount: &NginxServiceAccount{},
+				PodTemplate:    NginxPodTemplateSpec{ServiceAccountName: "my-sa"},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.serviceAccount: Forbidden: serviceAccount and podTemplate.serviceAccountName are mutually exclusive`,
+		},
+		{
+			name: "graceful shutdown and pre stop hook",
+			spec: NginxSpec{
+				Lifecycle: &NginxLifecycle{
+					PreStop:          &NginxLifecycleHandler{Exec: &corev1.ExecAction{Command: []string{"echo"}}},
+					GracefulShutdown: &NginxGracefulShutdown{SleepSeconds: 5},
+				},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.lifecycle.gracefulShutdown: Forbidden: gracefulShutdown and preStop are mutually exclusive`,
+		},
+		{
+			name: "load balancer IP on cluster IP service",
+			spec: NginxSpec{
+				Service: &NginxService{Type: corev1.ServiceTypeClusterIP, LoadBalancerIP: "10.0.0.1"},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.service.loadBalancerIP: Forbidden: may only be set when type is LoadBalancer`,
+		},
+		{
+			name: "malformed load balancer source range",
+			spec: NginxSpec{
+				Service: &NginxService{Type: corev1.ServiceTypeLoadBalancer, LoadBalancerSourceRanges: []string{"10.0.0.0/8", "192.168.0.1"}},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.service.loadBalancerSourceRanges[1]: Invalid value: "192.168.0.1": must be a valid CIDR, e.g. 10.0.0.0/8`,
+		},
+		{
+			name: "session affinity config without client IP affinity",
+			spec: NginxSpec{
+				Service: &NginxService{SessionAffinityConfig: &corev1.SessionAffinityConfig{}},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.service.sessionAffinityConfig: Forbidden: may only be set when sessionAffinity is ClientIP`,
+		},
+		{
+			name: "service port of unknown nginx port",
+			spec: NginxSpec{
+				Service: &NginxService{Ports: []NginxServicePort{{Name: "admin", Port: 9000}}},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.service.ports[0].name: Not found: "admin"`,
+		},
+		{
+			name: "headless internal service",
+			spec: NginxSpec{
+				InternalService: &NginxService{Headless: true},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.internalService.headless: Forbidden: may only be set on service`,
+		},
+		{
+			name: "success threshold on readiness probe",
+			spec: NginxSpec{
+				Healthcheck: &NginxHealthcheck{Readiness: &NginxProbe{SuccessThreshold: 3}, Liveness: &NginxProbe{SuccessThreshold: 1}},
+			},
+		},
+		{
+			name: "success threshold on liveness and startup probes",
+			spec: NginxSpec{
+				Healthcheck: &NginxHealthcheck{Liveness: &NginxProbe{SuccessThreshold: 2}, Startup: &NginxProbe{SuccessThreshold: 3}},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: [spec.healthcheck.liveness.successThreshold: Invalid value: 2: must be 1 on liveness and startup probes, spec.healthcheck.startup.successThreshold: Invalid value: 3: must be 1 on liveness and startup probes]`,
+		},
+		{
+			name: "negative probe period",
+			spec: NginxSpec{
+				Healthcheck: &NginxHealthcheck{Readiness: &NginxProbe{PeriodSeconds: -1}},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.healthcheck.readiness.periodSeconds: Invalid value: -1: must be greater than or equal to 0`,
+		},
+		{
+			name: "external traffic policy on cluster IP service",
+			spec: NginxSpec{
+				Service: &NginxService{ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal},
+			},
+			expectedError: `Nginx.nginx.tsuru.io "my-nginx" is invalid: spec.service.externalTrafficPolicy: Forbidden: may only be set when type is NodePort or LoadBalancer`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &Nginx{ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"}, Spec: tt.spec}
+			err := n.ValidateCreate()
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, tt.expectedError)
+		})
+	}
+}
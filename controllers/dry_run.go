@@ -0,0 +1,126 @@
+// Copyright 2026 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// dryRunClient is a client which logs the changes it's asked to make, along
+// with their diffs, and sends them to the API server in dry-run mode (so they
+// are validated but never persisted).
+type dryRunClient struct {
+	client.Client
+	log logr.Logger
+}
+
+var _ client.Client = &dryRunClient{}
+
+func newDryRunClient(c client.Client, log logr.Logger) *dryRunClient {
+	return &dryRunClient{Client: c, log: log.WithValues("dryRun", true)}
+}
+
+func (c *dryRunClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.logChange("Would create object", obj)
+	return c.Client.Create(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+func (c *dryRunClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.logUpdate(ctx, obj)
+	return c.Client.Update(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+func (c *dryRunClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.logPatch("Would patch object", obj, patch)
+	return c.Client.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+}
+
+func (c *dryRunClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	c.logChange("Would delete object", obj)
+	return c.Client.Delete(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+func (c *dryRunClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	c.logChange("Would delete all objects of kind", obj)
+	return c.Client.DeleteAllOf(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+func (c *dryRunClient) Status() client.StatusWriter {
+	return &dryRunStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+func (c *dryRunClient) logChange(msg string, obj client.Object) {
+	c.log.Info(msg, "kind", c.kind(obj), "name", obj.GetName(), "namespace", obj.GetNamespace())
+}
+
+// logUpdate logs the diff, as a JSON merge patch, between the stored object
+// and the given one.
+func (c *dryRunClient) logUpdate(ctx context.Context, obj client.Object) {
+	current, ok := obj.DeepCopyObject().(client.Object)
+	if !ok || c.Client.Get(ctx, client.ObjectKeyFromObject(obj), current) != nil {
+		c.logChange("Would update object", obj)
+		return
+	}
+
+	c.logPatch("Would update object", obj, client.MergeFrom(current))
+}
+
+func (c *dryRunClient) logPatch(msg string, obj client.Object, patch client.Patch) {
+	diff, err := patch.Data(obj)
+	if err != nil {
+		c.log.Error(err, "Unable to compute the diff", "kind", c.kind(obj), "name", obj.GetName(), "namespace", obj.GetNamespace())
+		return
+	}
+
+	c.log.Info(msg, "kind", c.kind(obj), "name", obj.GetName(), "namespace", obj.GetNamespace(), "diff", string(diff))
+}
+
+func (c *dryRunClient) kind(obj runtime.Object) string {
+	gvk, err := apiutil.GVKForObject(obj, c.Client.Scheme())
+	if err != nil {
+		return ""
+	}
+
+	return gvk.Kind
+}
+
+type dryRunStatusWriter struct {
+	client.StatusWriter
+	client *dryRunClient
+}
+
+func (w *dryRunStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	w.client.logUpdate(ctx, obj)
+	return w.StatusWriter.Update(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+func (w *dryRunStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	w.client.logPatch("Would patch object status", obj, patch)
+	return w.StatusWriter.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+}
+
+// dryRunEventRecorder prefixes the events with "[dry-run]", as the changes
+// they report were not applied.
+type dryRunEventRecorder struct {
+	record.EventRecorder
+}
+
+func (r *dryRunEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.EventRecorder.Event(object, eventtype, reason, "[dry-run] "+message)
+}
+
+func (r *dryRunEventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.Eventf(object, eventtype, reason, "[dry-run] "+messageFmt, args...)
+}
+
+func (r *dryRunEventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, "[dry-run] "+messageFmt, args...)
+}
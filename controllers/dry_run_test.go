@@ -0,0 +1,143 @@
+// Copyright 2026 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/tsuru/nginx-operator/api/v1alpha1"
+)
+
+func TestNginxReconciler_Reconcile_dryRun(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "my-nginx",
+			Namespace:   "default",
+			Annotations: map[string]string{"nginx.tsuru.io/dry-run": "true"},
+		},
+		Spec: v1alpha1.NginxSpec{Image: "nginx:stable"},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(nginx).
+		Build()
+
+	var logs []string
+	recorder := record.NewFakeRecorder(100)
+	r := &NginxReconciler{
+		Client:           client,
+		EventRecorder:    recorder,
+		Log:              funcr.New(func(prefix, args string) { logs = append(logs, args) }, funcr.Options{}),
+		AnnotationFilter: labels.Everything(),
+	}
+
+	_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "my-nginx", Namespace: "default"}})
+	require.NoError(t, err)
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &appsv1.Deployment{})
+	assert.True(t, errors.IsNotFound(err))
+
+	var got v1alpha1.Nginx
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got))
+	assert.Empty(t, got.Finalizers)
+	assert.Empty(t, got.Status.Conditions)
+
+	close(recorder.Events)
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+	assert.Contains(t, events, "Normal DeploymentCreated [dry-run] deployment created successfully")
+	assert.Contains(t, strings.Join(logs, "\n"), `"msg"="Would create object" "nginx"="default/my-nginx" "dryRun"=true "kind"="Deployment" "name"="my-nginx"`)
+}
+
+func TestNginxReconciler_Reconcile_dryRunDeletion(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "my-nginx",
+			Namespace:         "default",
+			Annotations:       map[string]string{"nginx.tsuru.io/dry-run": "true"},
+			DeletionTimestamp: func(t metav1.Time) *metav1.Time { return &t }(metav1.Now()),
+			Finalizers:        []string{"nginx.tsuru.io/finalizer"},
+		},
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx-service", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(nginx, service).
+		Build()
+
+	var logs []string
+	recorder := record.NewFakeRecorder(100)
+	r := &NginxReconciler{
+		Client:           client,
+		EventRecorder:    recorder,
+		Log:              funcr.New(func(prefix, args string) { logs = append(logs, args) }, funcr.Options{}),
+		AnnotationFilter: labels.Everything(),
+	}
+
+	result, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "my-nginx", Namespace: "default"}})
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, result, "should not requeue waiting for a Service which is never deleted")
+
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-service", Namespace: "default"}, &corev1.Service{}))
+
+	var got v1alpha1.Nginx
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got))
+	assert.Equal(t, []string{"nginx.tsuru.io/finalizer"}, got.Finalizers)
+
+	assert.Equal(t, "Normal ServiceDeleted [dry-run] service deleted successfully", <-recorder.Events)
+	assert.Contains(t, strings.Join(logs, "\n"), `"msg"="Would delete object" "nginx"="default/my-nginx" "dryRun"=true "kind"="Service" "name"="my-nginx-service"`)
+	assert.Contains(t, strings.Join(logs, "\n"), `"msg"="Would update object" "nginx"="default/my-nginx" "dryRun"=true "kind"="Nginx" "name"="my-nginx"`)
+}
+
+func TestDryRunClient_Update(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx-service", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(svc).
+		Build()
+
+	var logs []string
+	c := newDryRunClient(client, funcr.New(func(prefix, args string) { logs = append(logs, args) }, funcr.Options{}))
+
+	var current corev1.Service
+	require.NoError(t, c.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-service", Namespace: "default"}, &current))
+	current.Spec.Type = corev1.ServiceTypeLoadBalancer
+	require.NoError(t, c.Update(context.TODO(), &current))
+
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-service", Namespace: "default"}, &current))
+	assert.Equal(t, corev1.ServiceTypeClusterIP, current.Spec.Type)
+
+	require.Len(t, logs, 1)
+	assert.Contains(t, logs[0], `"msg"="Would update object"`)
+	assert.Contains(t, logs[0], `"kind"="Service"`)
+	assert.Contains(t, logs[0], `"diff"="{\"spec\":{\"type\":\"LoadBalancer\"}}"`)
+}
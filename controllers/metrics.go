@@ -0,0 +1,55 @@
+// Copyright 2020 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	nginxv1alpha1 "github.com/tsuru/nginx-operator/api/v1alpha1"
+)
+
+var nginxResourcesDesc = prometheus.NewDesc(
+	"nginx_operator_nginx_resources",
+	"Number of Nginx resources per namespace.",
+	[]string{"namespace"},
+	nil,
+)
+
+// NginxCollector is a Prometheus collector which reports the number of Nginx
+// resources per namespace. The reconcile counts, errors and durations are
+// already exported by the controller-runtime metrics.
+type NginxCollector struct {
+	Client client.Reader
+	Log    logr.Logger
+}
+
+var _ prometheus.Collector = &NginxCollector{}
+
+// Describe implements prometheus.Collector.
+func (c *NginxCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nginxResourcesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *NginxCollector) Collect(ch chan<- prometheus.Metric) {
+	var nginxList nginxv1alpha1.NginxList
+	if err := c.Client.List(context.Background(), &nginxList); err != nil {
+		c.Log.Error(err, "Unable to list Nginx resources")
+		return
+	}
+
+	count := make(map[string]int)
+	for _, n := range nginxList.Items {
+		count[n.Namespace]++
+	}
+
+	for ns, n := range count {
+		ch <- prometheus.MustNewConstMetric(nginxResourcesDesc, prometheus.GaugeValue, float64(n), ns)
+	}
+}
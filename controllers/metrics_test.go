@@ -0,0 +1,39 @@
+// Copyright 2020 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/tsuru/nginx-operator/api/v1alpha1"
+)
+
+func TestNginxCollector(t *testing.T) {
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithObjects(
+			&v1alpha1.Nginx{ObjectMeta: metav1.ObjectMeta{Name: "nginx-1", Namespace: "default"}},
+			&v1alpha1.Nginx{ObjectMeta: metav1.ObjectMeta{Name: "nginx-2", Namespace: "default"}},
+			&v1alpha1.Nginx{ObjectMeta: metav1.ObjectMeta{Name: "nginx-1", Namespace: "team-a"}},
+		).
+		Build()
+
+	c := &NginxCollector{Client: client, Log: logr.Discard()}
+
+	expected := `
+# HELP nginx_operator_nginx_resources Number of Nginx resources per namespace.
+# TYPE nginx_operator_nginx_resources gauge
+nginx_operator_nginx_resources{namespace="default"} 2
+nginx_operator_nginx_resources{namespace="team-a"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected)))
+}
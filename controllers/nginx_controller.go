@@ -6,26 +6,46 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"io"
 	"reflect"
+	"regexp"
 	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/tsuru/nginx-operator/api/v1alpha1"
 	nginxv1alpha1 "github.com/tsuru/nginx-operator/api/v1alpha1"
@@ -34,6 +54,50 @@ import (
 
 const (
 	gcpNetworkTierAnnotationKey = "cloud.google.com/network-tier"
+
+	// Annotation key set on the pod template to roll out the pods whenever
+	// any ConfigMap referenced by the Nginx changes.
+	configChecksumAnnotationKey = "nginx.tsuru.io/config-checksum"
+
+	// Annotation key set on the pod template to roll out the pods whenever
+	// any TLS Secret referenced by the Nginx changes e.g. certificate renewal.
+	tlsChecksumAnnotationKey = "nginx.tsuru.io/tls-checksum"
+
+	// Annotation key set on the Services, when manual changes are tolerated,
+	// to tell the changes on the desired Service from the manual ones.
+	serviceChecksumAnnotationKey = "nginx.tsuru.io/service-checksum"
+
+	// Annotation keys set on the Deployment while surging a nginx version
+	// upgrade, see spec.strategy.surgeOnUpgrade.
+	upgradeFromVersionAnnotationKey = "nginx.tsuru.io/upgrade-from-version"
+	upgradeToVersionAnnotationKey   = "nginx.tsuru.io/upgrade-to-version"
+
+	loadBalancerPendingRequeueInterval = 15 * time.Second
+
+	// Finalizer set on Nginx resources in order to tear down resources which
+	// need an explicit cleanup (e.g. cloud load balancers) before deletion.
+	nginxFinalizer = "nginx.tsuru.io/finalizer"
+
+	finalizerRequeueInterval = 5 * time.Second
+
+	defaultRetryBaseDelay = time.Second
+	defaultRetryMaxDelay  = 5 * time.Minute
+
+	// Annotation key which, when set to "true", makes the operator stop
+	// changing the resources of a Nginx (its status is still updated).
+	pausedAnnotationKey = "nginx.tsuru.io/paused"
+
+	// Annotation key which, when set to "true", makes the operator adopt a
+	// pre-existing Deployment named after the Nginx (e.g. created by hand)
+	// rather than failing to reconcile it.
+	adoptAnnotationKey = "nginx.tsuru.io/adopt"
+
+	// Annotation key which, when set to "true", makes the operator only log
+	// (and emit as events) the changes it would make on a Nginx's resources.
+	dryRunAnnotationKey = "nginx.tsuru.io/dry-run"
+
+	// Path prefix used by the ACME HTTP-01 challenges.
+	acmeChallengePathPrefix = "/.well-known/acme-challenge/"
 )
 
 // NginxReconciler reconciles a Nginx object
@@ -43,293 +107,2213 @@ type NginxReconciler struct {
 	Log              logr.Logger
 	Scheme           *runtime.Scheme
 	AnnotationFilter labels.Selector
+
+	// EnableGatewayAPI turns on the management of Gateway API HTTPRoutes for
+	// the Nginx resources. The Gateway API CRDs must be installed in the
+	// cluster.
+	EnableGatewayAPI bool
+
+	// EnableServiceMonitor turns on the management of Prometheus Operator
+	// ServiceMonitors for the Nginx resources with metrics enabled. The
+	// ServiceMonitor CRD must be installed in the cluster.
+	EnableServiceMonitor bool
+
+	// MaxConcurrentReconciles is the maximum number of Nginx resources
+	// reconciled in parallel. Defaults to 1.
+	MaxConcurrentReconciles int
+
+	// RetryBaseDelay and RetryMaxDelay bound the exponential backoff applied,
+	// per Nginx resource, to retries of failed reconciles. Defaults to
+	// 1s and 5m respectively.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+
+	// DryRun makes the operator only log (and emit as events) the changes it
+	// would make on every Nginx, sending them to the API server in dry-run
+	// mode.
+	DryRun bool
+
+	// APIReader reads the ConfigMaps and Secrets referenced by the Nginxes
+	// straight from the API server, so their content isn't kept in the cache.
+	// Defaults to the Client.
+	APIReader client.Reader
+
+	// ConfigLoader provides the operator-wide defaults of the Nginx
+	// resources, every Nginx is reconciled again when they change. Optional.
+	ConfigLoader *OperatorConfigLoader
 }
 
 // +kubebuilder:rbac:groups=nginx.tsuru.io,resources=nginxes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=nginx.tsuru.io,resources=nginxes/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=nginx.tsuru.io,resources=nginxes/finalizers,verbs=update
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete;deletecollection
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;delete
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;delete
-// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;update;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
 func (r *NginxReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&nginxv1alpha1.Nginx{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.DaemonSet{}).
 		Owns(&networkingv1.Ingress{}).
 		Owns(&corev1.Service{}).
-		Complete(r)
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&batchv1.Job{}).
+		// NOTE: only the metadata of ConfigMaps and Secrets is cached, their
+		// content is read through the APIReader.
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.nginxesForConfigMap), builder.OnlyMetadata).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.nginxesForSecret), builder.OnlyMetadata).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			RateLimiter:             newRateLimiter(r.RetryBaseDelay, r.RetryMaxDelay),
+		})
+
+	if r.ConfigLoader != nil {
+		b = b.Watches(&source.Channel{Source: r.ConfigLoader.changes}, handler.EnqueueRequestsFromMapFunc(r.allNginxes))
+	}
+
+	if r.EnableGatewayAPI {
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(k8s.HTTPRouteGroupVersionKind)
+		b = b.Owns(route)
+	}
+
+	if r.EnableServiceMonitor {
+		sm := &unstructured.Unstructured{}
+		sm.SetGroupVersionKind(k8s.ServiceMonitorGroupVersionKind)
+		b = b.Owns(sm)
+	}
+
+	return b.Complete(r)
+}
+
+// newRateLimiter returns a rate limiter which backs off exponentially on the
+// failures of each Nginx resource (so a single broken resource can't hot-loop
+// the operator) while limiting the overall retry rate (so it can't starve the
+// other resources either).
+func newRateLimiter(baseDelay, maxDelay time.Duration) workqueue.RateLimiter {
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
+
+// nginxesForConfigMap returns a reconcile request for every Nginx (in the same
+// namespace) which uses the given ConfigMap either as config or as extra files.
+func (r *NginxReconciler) nginxesForConfigMap(o client.Object) []reconcile.Request {
+	var nginxList nginxv1alpha1.NginxList
+	if err := r.Client.List(context.Background(), &nginxList, client.InNamespace(o.GetNamespace())); err != nil {
+		r.Log.Error(err, "Unable to list Nginx resources", "namespace", o.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, n := range nginxList.Items {
+		if !usesConfigMap(&n, o.GetName()) {
+			continue
+		}
+
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: n.Name, Namespace: n.Namespace},
+		})
+	}
+
+	return requests
+}
+
+// nginxesForSecret returns a reconcile request for every Nginx (in the same
+// namespace) which uses the given Secret as TLS certificate.
+func (r *NginxReconciler) nginxesForSecret(o client.Object) []reconcile.Request {
+	var nginxList nginxv1alpha1.NginxList
+	if err := r.Client.List(context.Background(), &nginxList, client.InNamespace(o.GetNamespace())); err != nil {
+		r.Log.Error(err, "Unable to list Nginx resources", "namespace", o.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, n := range nginxList.Items {
+		if !usesSecret(&n, o.GetName()) {
+			continue
+		}
+
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: n.Name, Namespace: n.Namespace},
+		})
+	}
+
+	return requests
+}
+
+func usesSecret(nginx *nginxv1alpha1.Nginx, name string) bool {
+	for _, t := range nginx.Spec.TLS {
+		if t.SecretName == name || (t.ClientAuth != nil && t.ClientAuth.CASecretName == name) {
+			return true
+		}
+	}
+
+	_, secrets := configSources(nginx)
+	return slices.Contains(secrets, name)
+}
+
+func usesConfigMap(nginx *nginxv1alpha1.Nginx, name string) bool {
+	configMaps, _ := configSources(nginx)
+	return slices.Contains(configMaps, name)
+}
+
+// configSources returns the names of the ConfigMaps and Secrets, other than
+// the TLS ones, read by the nginx pods: the config and its extra files, the
+// log shipper config and the ones referenced by the nginx container env.
+func configSources(nginx *nginxv1alpha1.Nginx) (configMaps, secrets []string) {
+	if c := nginx.Spec.Config; c != nil && c.Kind == nginxv1alpha1.ConfigKindConfigMap {
+		configMaps = append(configMaps, c.Name)
+	}
+
+	if nginx.Spec.ExtraFiles != nil {
+		configMaps = append(configMaps, nginx.Spec.ExtraFiles.Name)
+	}
+
+	if c := nginx.Spec.Config; c != nil && c.ExtraFiles != nil {
+		for _, src := range c.ExtraFiles.Sources {
+			if src.ConfigMap != nil {
+				configMaps = append(configMaps, src.ConfigMap.Name)
+			}
+
+			if src.Secret != nil {
+				secrets = append(secrets, src.Secret.Name)
+			}
+		}
+	}
+
+	if l := nginx.Spec.Logging; l != nil && l.Sidecar != nil {
+		configMaps = append(configMaps, l.Sidecar.ConfigMapName)
+	}
+
+	for _, e := range nginx.Spec.PodTemplate.EnvFrom {
+		if e.ConfigMapRef != nil {
+			configMaps = append(configMaps, e.ConfigMapRef.Name)
+		}
+
+		if e.SecretRef != nil {
+			secrets = append(secrets, e.SecretRef.Name)
+		}
+	}
+
+	for _, e := range nginx.Spec.PodTemplate.Env {
+		if e.ValueFrom == nil {
+			continue
+		}
+
+		if ref := e.ValueFrom.ConfigMapKeyRef; ref != nil && !slices.Contains(configMaps, ref.Name) {
+			configMaps = append(configMaps, ref.Name)
+		}
+
+		if ref := e.ValueFrom.SecretKeyRef; ref != nil && !slices.Contains(secrets, ref.Name) {
+			secrets = append(secrets, ref.Name)
+		}
+	}
+
+	return configMaps, secrets
+}
+
+func (r *NginxReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("nginx", req.NamespacedName)
+
+	var instance nginxv1alpha1.Nginx
+	err := r.Client.Get(ctx, req.NamespacedName, &instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Nginx resource not found, skipping reconcile")
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "Unable to get Nginx resource")
+		return ctrl.Result{}, err
+	}
+
+	if !r.shouldManageNginx(&instance) {
+		log.V(1).Info("Nginx resource doesn't match annotations filters, skipping it")
+		return ctrl.Result{Requeue: true, RequeueAfter: 5 * time.Minute}, nil
+	}
+
+	if r.DryRun || instance.Annotations[dryRunAnnotationKey] == "true" {
+		dryRun := *r
+		dryRun.DryRun = true
+		dryRun.Client = newDryRunClient(r.Client, log)
+		dryRun.EventRecorder = &dryRunEventRecorder{EventRecorder: r.EventRecorder}
+		r = &dryRun
+	}
+
+	if !instance.DeletionTimestamp.IsZero() {
+		result, err := r.finalizeNginx(ctx, &instance)
+		if err != nil {
+			log.Error(err, "Fail to finalize Nginx resource")
+		}
+
+		return result, err
+	}
+
+	if !controllerutil.ContainsFinalizer(&instance, nginxFinalizer) {
+		controllerutil.AddFinalizer(&instance, nginxFinalizer)
+		if err := r.Client.Update(ctx, &instance); err != nil {
+			log.Error(err, "Unable to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
+	r.ConfigLoader.Config().ApplyDefaults(&instance.Spec)
+
+	if isNginxPaused(&instance) {
+		log.V(1).Info("Nginx resource is paused, skipping changes on its resources")
+	} else if err := r.reconcileNginx(ctx, &instance); err != nil {
+		log.Error(err, "Fail to reconcile")
+
+		if statusErr := r.setReconcileFailedCondition(ctx, &instance, err); statusErr != nil {
+			log.Error(statusErr, "Fail to set degraded condition")
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	if err := r.refreshStatus(ctx, &instance); err != nil {
+		log.Error(err, "Fail to refresh status subresource")
+		return ctrl.Result{}, err
+	}
+
+	if isLoadBalancerAddressPending(&instance) {
+		log.V(1).Info("Load balancer address not assigned yet, requeueing")
+		return ctrl.Result{RequeueAfter: loadBalancerPendingRequeueInterval}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func isNginxPaused(nginx *nginxv1alpha1.Nginx) bool {
+	return nginx.Annotations[pausedAnnotationKey] == "true"
+}
+
+// finalizeNginx tears down the resources which need an explicit cleanup before
+// removing the finalizer. The Services are deleted first so the cloud provider
+// can release their load balancers while the Nginx resource still exists,
+// unless the deletion policy orphans them.
+func (r *NginxReconciler) finalizeNginx(ctx context.Context, nginx *nginxv1alpha1.Nginx) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(nginx, nginxFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	pending, err := r.finalizeService(ctx, nginx, k8s.NewService(nginx).Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// NOTE: the internal Service, e.g. a private load balancer, follows the
+	// same deletion policy. It's only considered while enabled, otherwise
+	// it's already gone or not managed by this Nginx.
+	if nginx.Spec.InternalService != nil {
+		internalPending, err := r.finalizeService(ctx, nginx, k8s.InternalServiceName(nginx.Name))
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		pending = pending || internalPending
+	}
+
+	// NOTE: waiting the Services to go away, e.g. while the cloud provider
+	// releases their load balancers. In dry-run mode they're never deleted,
+	// so the would-be finalizer removal is logged instead and the Nginx is
+	// left terminating until the dry-run mode is turned off.
+	if pending && !r.DryRun {
+		return ctrl.Result{RequeueAfter: finalizerRequeueInterval}, nil
+	}
+
+	controllerutil.RemoveFinalizer(nginx, nginxFinalizer)
+	if err = r.Client.Update(ctx, nginx); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to remove finalizer: %w", err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// finalizeService orphans or deletes the Service as set on the deletion
+// policy, returning whether it's still to be deleted.
+func (r *NginxReconciler) finalizeService(ctx context.Context, nginx *nginxv1alpha1.Nginx, name string) (bool, error) {
+	var service corev1.Service
+	err := r.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: nginx.Namespace}, &service)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve Service: %w", err)
+	}
+
+	if nginx.Spec.DeletionPolicy != nil && nginx.Spec.DeletionPolicy.Service == v1alpha1.DeletionPolicyOrphan {
+		return false, r.orphanService(ctx, nginx, &service)
+	}
+
+	if service.DeletionTimestamp.IsZero() {
+		if err = r.Client.Delete(ctx, &service); err != nil && !errors.IsNotFound(err) {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ServiceDeletionFailed", "failed to delete Service: %s", err)
+			return false, err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "ServiceDeleted", "service deleted successfully")
+	}
+
+	return true, nil
+}
+
+// orphanService removes the Nginx owner reference from the Service, so the
+// garbage collector leaves it behind.
+func (r *NginxReconciler) orphanService(ctx context.Context, nginx *nginxv1alpha1.Nginx, service *corev1.Service) error {
+	var owners []metav1.OwnerReference
+	for _, owner := range service.OwnerReferences {
+		if owner.UID != nginx.UID {
+			owners = append(owners, owner)
+		}
+	}
+
+	if len(owners) == len(service.OwnerReferences) {
+		return nil
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	service.OwnerReferences = owners
+
+	if err := r.Client.Patch(ctx, service, patch); err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ServiceOrphanFailed", "failed to orphan Service: %s", err)
+		return fmt.Errorf("failed to remove owner reference from Service: %w", err)
+	}
+
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "ServiceOrphaned", "service orphaned successfully")
+	return nil
+}
+
+// isLoadBalancerAddressPending returns whether the Nginx requires a load
+// balancer Service which doesn't have an external address assigned yet.
+func isLoadBalancerAddressPending(nginx *nginxv1alpha1.Nginx) bool {
+	if nginx.Spec.Service == nil || nginx.Spec.Service.Type != corev1.ServiceTypeLoadBalancer {
+		return false
+	}
+
+	var services int
+	for _, svc := range nginx.Status.Services {
+		if svc.Name == k8s.HeadlessServiceName(nginx.Name) || svc.Name == k8s.InternalServiceName(nginx.Name) {
+			continue
+		}
+
+		if len(svc.IPs) == 0 && len(svc.Hostnames) == 0 {
+			return true
+		}
+
+		services++
+	}
+
+	return services == 0
+}
+
+func (r *NginxReconciler) reconcileNginx(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	if err := r.reconcileServiceAccount(ctx, nginx); err != nil {
+		return err
+	}
+
+	if err := r.reconcileWorkload(ctx, nginx); err != nil {
+		return err
+	}
+
+	if err := r.reconcileService(ctx, nginx); err != nil {
+		return err
+	}
+
+	if err := r.reconcileInternalService(ctx, nginx); err != nil {
+		return err
+	}
+
+	if err := r.reconcileHeadlessService(ctx, nginx); err != nil {
+		return err
+	}
+
+	if err := r.reconcileIngress(ctx, nginx); err != nil {
+		return err
+	}
+
+	if err := r.reconcileHorizontalPodAutoscaler(ctx, nginx); err != nil {
+		return err
+	}
+
+	if err := r.reconcilePodDisruptionBudget(ctx, nginx); err != nil {
+		return err
+	}
+
+	if err := r.reconcileNetworkPolicy(ctx, nginx); err != nil {
+		return err
+	}
+
+	if r.EnableGatewayAPI {
+		if err := r.reconcileHTTPRoute(ctx, nginx); err != nil {
+			return err
+		}
+	}
+
+	if r.EnableServiceMonitor {
+		if err := r.reconcileServiceMonitor(ctx, nginx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *NginxReconciler) reconcileDeployment(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	newDeploy, err := k8s.NewDeployment(nginx)
+	if err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "InvalidNginxSpec", "failed to build Deployment: %s", err)
+		return fmt.Errorf("failed to build Deployment from Nginx: %w", err)
+	}
+
+	if err = r.setChecksumAnnotations(ctx, nginx, &newDeploy.Spec.Template); err != nil {
+		return err
+	}
+
+	var currentDeploy appsv1.Deployment
+	err = r.Client.Get(ctx, types.NamespacedName{Name: newDeploy.Name, Namespace: newDeploy.Namespace}, &currentDeploy)
+	if errors.IsNotFound(err) {
+		if err = r.Client.Create(ctx, newDeploy); err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "DeploymentCreationFailed", "failed to create Deployment: %s", err)
+			return err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "DeploymentCreated", "deployment created successfully")
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to retrieve Deployment: %w", err)
+	}
+
+	setUpgradeStrategy(nginx, &currentDeploy, newDeploy)
+
+	var adopting bool
+	existingNginxSpec, err := k8s.ExtractNginxSpec(currentDeploy.ObjectMeta)
+	switch {
+	case err != nil && metav1.IsControlledBy(&currentDeploy, nginx):
+		return fmt.Errorf("failed to extract Nginx spec from Deployment annotations: %w", err)
+
+	case err != nil:
+		// NOTE: Deployment not created by the operator, e.g. by hand.
+		if err = canAdopt(nginx, &currentDeploy, "Deployment"); err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(currentDeploy.Spec.Selector, newDeploy.Spec.Selector) {
+			return fmt.Errorf("cannot adopt Deployment %q: its pod selector differs from the Nginx one and cannot be changed", currentDeploy.Name)
+		}
+
+		adopting = true
+
+	case reflect.DeepEqual(nginx.Spec, existingNginxSpec) && !deploymentDiverged(&currentDeploy, newDeploy) && !upgradeDiverged(&currentDeploy, newDeploy):
+		return nil
+
+	case reflect.DeepEqual(nginx.Spec, existingNginxSpec) && nginx.Spec.DriftPolicy == v1alpha1.DriftPolicyTolerate && !checksumsDiverged(&currentDeploy.Spec.Template, &newDeploy.Spec.Template) && !upgradeDiverged(&currentDeploy, newDeploy):
+		return nil
+	}
+
+	if nginx.Spec.ValidateConfig {
+		valid, err := r.validateConfig(ctx, nginx, &currentDeploy, newDeploy)
+		if err != nil {
+			return err
+		}
+
+		if !valid {
+			// NOTE: waiting the validation Job to finish, its changes trigger
+			// a new reconciliation.
+			return nil
+		}
+	}
+
+	replicas := currentDeploy.Spec.Replicas
+
+	patch := client.StrategicMergeFrom(currentDeploy.DeepCopy())
+	currentDeploy.Spec = newDeploy.Spec
+
+	if newDeploy.Spec.Replicas == nil {
+		// NOTE: replicas field is set to nil whenever it's managed by some
+		// autoscaler controller e.g HPA.
+		currentDeploy.Spec.Replicas = replicas
+	}
+
+	if adopting {
+		currentDeploy.Labels = mergeMap(currentDeploy.Labels, newDeploy.Labels)
+		currentDeploy.OwnerReferences = append(currentDeploy.OwnerReferences, newDeploy.OwnerReferences...)
+	}
+
+	for _, key := range []string{upgradeFromVersionAnnotationKey, upgradeToVersionAnnotationKey} {
+		if value, ok := newDeploy.Annotations[key]; ok {
+			currentDeploy.Annotations = mergeMap(currentDeploy.Annotations, map[string]string{key: value})
+		} else {
+			delete(currentDeploy.Annotations, key)
+		}
+	}
+
+	err = k8s.SetNginxSpec(&currentDeploy.ObjectMeta, nginx.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to set Nginx spec in Deployment annotations: %w", err)
+	}
+
+	err = r.Client.Patch(ctx, &currentDeploy, patch)
+	if err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "DeploymentUpdateFailed", "failed to update Deployment: %s", err)
+		return fmt.Errorf("failed to patch Deployment: %w", err)
+	}
+
+	if adopting {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "DeploymentAdopted", "deployment adopted successfully")
+		return nil
+	}
+
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "DeploymentUpdated", "deployment updated successfully")
+	return nil
+}
+
+func (r *NginxReconciler) reconcileDaemonSet(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	newDS, err := k8s.NewDaemonSet(nginx)
+	if err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "InvalidNginxSpec", "failed to build DaemonSet: %s", err)
+		return fmt.Errorf("failed to build DaemonSet from Nginx: %w", err)
+	}
+
+	if err = r.setChecksumAnnotations(ctx, nginx, &newDS.Spec.Template); err != nil {
+		return err
+	}
+
+	var currentDS appsv1.DaemonSet
+	err = r.Client.Get(ctx, types.NamespacedName{Name: newDS.Name, Namespace: newDS.Namespace}, &currentDS)
+	if errors.IsNotFound(err) {
+		if err = r.Client.Create(ctx, newDS); err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "DaemonSetCreationFailed", "failed to create DaemonSet: %s", err)
+			return err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "DaemonSetCreated", "daemonset created successfully")
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to retrieve DaemonSet: %w", err)
+	}
+
+	existingNginxSpec, err := k8s.ExtractNginxSpec(currentDS.ObjectMeta)
+	if err != nil {
+		return fmt.Errorf("failed to extract Nginx spec from DaemonSet annotations: %w", err)
+	}
+
+	if reflect.DeepEqual(nginx.Spec, existingNginxSpec) && !podTemplateDiverged(&currentDS.Spec.Template, &newDS.Spec.Template) {
+		return nil
+	}
+
+	patch := client.StrategicMergeFrom(currentDS.DeepCopy())
+	currentDS.Spec.Template = newDS.Spec.Template
+
+	err = k8s.SetNginxSpec(&currentDS.ObjectMeta, nginx.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to set Nginx spec in DaemonSet annotations: %w", err)
+	}
+
+	err = r.Client.Patch(ctx, &currentDS, patch)
+	if err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "DaemonSetUpdateFailed", "failed to update DaemonSet: %s", err)
+		return fmt.Errorf("failed to patch DaemonSet: %w", err)
+	}
+
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "DaemonSetUpdated", "daemonset updated successfully")
+	return nil
+}
+
+// reconcileWorkload reconciles the Deployment or the DaemonSet running the
+// nginx pods, removing the other one left behind by a deploymentMode change.
+func (r *NginxReconciler) reconcileWorkload(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	key := types.NamespacedName{Name: nginx.Name, Namespace: nginx.Namespace}
+
+	if nginx.Spec.DeploymentMode == nginxv1alpha1.DeploymentModeDaemonSet {
+		if err := r.reconcileDaemonSet(ctx, nginx); err != nil {
+			return err
+		}
+
+		return r.deleteOwnedObject(ctx, nginx, key, &appsv1.Deployment{}, "Deployment", "deployment")
+	}
+
+	if err := r.reconcileDeployment(ctx, nginx); err != nil {
+		return err
+	}
+
+	return r.deleteOwnedObject(ctx, nginx, key, &appsv1.DaemonSet{}, "DaemonSet", "daemonset")
+}
+
+// deleteOwnedObject deletes the object no longer required by the Nginx, e.g.
+// once its feature is disabled. Objects not controlled by the Nginx, like the
+// ones created by users with the same name, are left untouched. The kind is
+// used on the event reasons while desc describes the object on messages.
+func (r *NginxReconciler) deleteOwnedObject(ctx context.Context, nginx *nginxv1alpha1.Nginx, key types.NamespacedName, obj client.Object, kind, desc string) error {
+	err := r.Client.Get(ctx, key, obj)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to retrieve %s: %w", desc, err)
+	}
+
+	if !metav1.IsControlledBy(obj, nginx) {
+		return nil
+	}
+
+	if err = r.Client.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, kind+"DeletionFailed", "failed to delete %s: %s", desc, err)
+		return err
+	}
+
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, kind+"Deleted", "%s deleted successfully", desc)
+	return nil
+}
+
+// setChecksumAnnotations sets the checksums of the config and TLS objects on
+// the pod template, so the pods roll out whenever their contents change.
+func (r *NginxReconciler) setChecksumAnnotations(ctx context.Context, nginx *nginxv1alpha1.Nginx, template *corev1.PodTemplateSpec) error {
+	checksum, err := r.configChecksum(ctx, nginx)
+	if err != nil {
+		return fmt.Errorf("failed to compute the config checksum: %w", err)
+	}
+
+	if checksum != "" {
+		setPodTemplateAnnotation(template, configChecksumAnnotationKey, checksum)
+	}
+
+	checksum, err = r.tlsChecksum(ctx, nginx)
+	if err != nil {
+		return fmt.Errorf("failed to compute the TLS checksum: %w", err)
+	}
+
+	if checksum != "" {
+		setPodTemplateAnnotation(template, tlsChecksumAnnotationKey, checksum)
+	}
+
+	return nil
+}
+
+// validateConfig checks the nginx configuration of the desired Deployment on a
+// short-lived Job whenever it differs from the current one. It returns false
+// while the validation did not succeed yet.
+func (r *NginxReconciler) validateConfig(ctx context.Context, nginx *nginxv1alpha1.Nginx, current, desired *appsv1.Deployment) (bool, error) {
+	fingerprint := configFingerprint(desired)
+	if configFingerprint(current) == fingerprint {
+		return true, nil
+	}
+
+	newJob := k8s.NewConfigValidationJob(nginx, desired, configValidationJobName(nginx.Name, fingerprint))
+
+	var job batchv1.Job
+	err := r.Client.Get(ctx, types.NamespacedName{Name: newJob.Name, Namespace: newJob.Namespace}, &job)
+	if errors.IsNotFound(err) {
+		if err = r.Client.Create(ctx, newJob); err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ConfigValidationCreationFailed", "failed to create config validation Job: %s", err)
+			return false, err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "ConfigValidationStarted", "validating nginx configuration on Job %s", newJob.Name)
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve config validation Job: %w", err)
+	}
+
+	if jobConditionTrue(&job, batchv1.JobFailed) {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "InvalidNginxConfig", "nginx configuration is invalid, see the logs of Job %s", job.Name)
+		return false, fmt.Errorf("nginx configuration is invalid, see the logs of Job %s", job.Name)
+	}
+
+	if !jobConditionTrue(&job, batchv1.JobComplete) {
+		return false, nil
+	}
+
+	err = r.Client.DeleteAllOf(ctx, &batchv1.Job{},
+		client.InNamespace(nginx.Namespace),
+		client.MatchingLabels(k8s.LabelsForConfigValidation(nginx.Name)),
+		client.PropagationPolicy(metav1.DeletePropagationBackground),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete config validation Jobs: %w", err)
+	}
+
+	return true, nil
+}
+
+// configValidationJobName returns the name of the Job validating the config
+// with the given fingerprint. The Nginx name is truncated so the Job name fits
+// on the job-name label of its pods.
+func configValidationJobName(nginxName, fingerprint string) string {
+	suffix := "-config-check-" + fingerprint[:10]
+	if max := validation.DNS1123LabelMaxLength - len(suffix); len(nginxName) > max {
+		nginxName = strings.TrimRight(nginxName[:max], "-.")
+	}
+
+	return nginxName + suffix
+}
+
+// configFingerprint returns a digest of everything in the Deployment which
+// may change the nginx configuration, i.e. image, config and certificates.
+func configFingerprint(dep *appsv1.Deployment) string {
+	h := sha256.New()
+	if containers := dep.Spec.Template.Spec.Containers; len(containers) > 0 {
+		fmt.Fprintf(h, "image=%s\n", containers[0].Image)
+	}
+
+	for _, key := range []string{configChecksumAnnotationKey, tlsChecksumAnnotationKey, k8s.CustomNginxConfigAnnotation} {
+		fmt.Fprintf(h, "%s=%s\n", key, dep.Spec.Template.Annotations[key])
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func jobConditionTrue(job *batchv1.Job, t batchv1.JobConditionType) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == t && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *NginxReconciler) apiReader() client.Reader {
+	if r.APIReader == nil {
+		return r.Client
+	}
+
+	return r.APIReader
+}
+
+// configChecksum returns a digest of the ConfigMaps and Secrets (but the TLS
+// ones, see tlsChecksum) referenced by the Nginx, or an empty string when
+// there is none. Objects not found are skipped.
+func (r *NginxReconciler) configChecksum(ctx context.Context, nginx *nginxv1alpha1.Nginx) (string, error) {
+	names, secrets := configSources(nginx)
+	if len(names) == 0 && len(secrets) == 0 {
+		return "", nil
+	}
+
+	h := sha256.New()
+	for _, name := range names {
+		var cm corev1.ConfigMap
+		err := r.apiReader().Get(ctx, types.NamespacedName{Name: name, Namespace: nginx.Namespace}, &cm)
+		if errors.IsNotFound(err) {
+			continue
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "configmap=%s\n", name)
+		writeStringData(h, cm.Data)
+		writeBinaryData(h, cm.BinaryData)
+	}
+
+	for _, name := range secrets {
+		var secret corev1.Secret
+		err := r.apiReader().Get(ctx, types.NamespacedName{Name: name, Namespace: nginx.Namespace}, &secret)
+		if errors.IsNotFound(err) {
+			continue
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "secret=%s\n", name)
+		writeBinaryData(h, secret.Data)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// tlsChecksum returns a digest of the TLS Secrets referenced by the Nginx, or
+// an empty string when there is none. Secrets not found are skipped.
+func (r *NginxReconciler) tlsChecksum(ctx context.Context, nginx *nginxv1alpha1.Nginx) (string, error) {
+	if len(nginx.Spec.TLS) == 0 {
+		return "", nil
+	}
+
+	var names []string
+	for _, t := range nginx.Spec.TLS {
+		names = append(names, t.SecretName)
+
+		if t.ClientAuth != nil {
+			names = append(names, t.ClientAuth.CASecretName)
+		}
+	}
+
+	h := sha256.New()
+	for _, name := range names {
+		var secret corev1.Secret
+		err := r.apiReader().Get(ctx, types.NamespacedName{Name: name, Namespace: nginx.Namespace}, &secret)
+		if errors.IsNotFound(err) {
+			continue
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "secret=%s\n", name)
+		writeBinaryData(h, secret.Data)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func writeStringData(w io.Writer, data map[string]string) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s=%s\n", key, data[key])
+	}
+}
+
+func writeBinaryData(w io.Writer, data map[string][]byte) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s=%x\n", key, data[key])
+	}
+}
+
+// setPodTemplateAnnotation sets an annotation on the pod template without
+// changing the annotations map shared with the Nginx spec.
+func setPodTemplateAnnotation(template *corev1.PodTemplateSpec, key, value string) {
+	annotations := map[string]string{key: value}
+	for k, v := range template.Annotations {
+		if k != key {
+			annotations[k] = v
+		}
+	}
+	template.Annotations = annotations
+}
+
+// deploymentDiverged returns whether the fields managed by the operator on the
+// current Deployment differ from the desired one, e.g. after a manual edit.
+func deploymentDiverged(current, desired *appsv1.Deployment) bool {
+	if desired.Spec.Replicas != nil && !reflect.DeepEqual(current.Spec.Replicas, desired.Spec.Replicas) {
+		return true
+	}
+
+	if !equality.Semantic.DeepDerivative(desired.Spec.Strategy, current.Spec.Strategy) {
+		return true
+	}
+
+	return podTemplateDiverged(&current.Spec.Template, &desired.Spec.Template)
+}
+
+// setUpgradeStrategy replaces the desired Deployment strategy by one which
+// surges the new pods to the full capacity when the Nginx image changes the
+// nginx major or minor version, until the rollout finishes.
+func setUpgradeStrategy(nginx *nginxv1alpha1.Nginx, current, desired *appsv1.Deployment) {
+	if nginx.Spec.Strategy == nil || !nginx.Spec.Strategy.SurgeOnUpgrade {
+		return
+	}
+
+	currentVersion, desiredVersion := nginxVersion(nginxImage(&current.Spec.Template)), nginxVersion(nginxImage(&desired.Spec.Template))
+	from, to := current.Annotations[upgradeFromVersionAnnotationKey], current.Annotations[upgradeToVersionAnnotationKey]
+
+	switch {
+	case currentVersion != "" && desiredVersion != "" && minorVersion(currentVersion) != minorVersion(desiredVersion):
+		from, to = currentVersion, desiredVersion
+
+	case to != "" && to == desiredVersion && deploymentRollingOut(current):
+		// NOTE: keeping the surge strategy until the upgrade finishes.
+
+	default:
+		return
+	}
+
+	maxSurge, maxUnavailable := intstr.FromString("100%"), intstr.FromInt(0)
+	desired.Spec.Strategy = appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxSurge:       &maxSurge,
+			MaxUnavailable: &maxUnavailable,
+		},
+	}
+
+	desired.Annotations = mergeMap(desired.Annotations, map[string]string{
+		upgradeFromVersionAnnotationKey: from,
+		upgradeToVersionAnnotationKey:   to,
+	})
+}
+
+// upgradeDiverged returns whether the surged upgrade on the current
+// Deployment differs from the desired one, e.g. it has just finished.
+func upgradeDiverged(current, desired *appsv1.Deployment) bool {
+	return current.Annotations[upgradeFromVersionAnnotationKey] != desired.Annotations[upgradeFromVersionAnnotationKey] ||
+		current.Annotations[upgradeToVersionAnnotationKey] != desired.Annotations[upgradeToVersionAnnotationKey]
+}
+
+func nginxImage(template *corev1.PodTemplateSpec) string {
+	for _, c := range template.Spec.Containers {
+		if c.Name == "nginx" {
+			return c.Image
+		}
+	}
+
+	return ""
+}
+
+var nginxVersionRegexp = regexp.MustCompile(`^(\d+)\.(\d+)(\.\d+)?`)
+
+// nginxVersion returns the nginx version from the image tag, e.g. "1.25.3"
+// from "nginx:1.25.3-alpine", or an empty string if the tag has none.
+func nginxVersion(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+
+	return nginxVersionRegexp.FindString(strings.TrimPrefix(image[i+1:], "v"))
+}
+
+// minorVersion returns the major and minor parts of the version, e.g. "1.25"
+// from "1.25.3".
+func minorVersion(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	return strings.Join(parts[:2], ".")
+}
+
+// checksumsDiverged returns whether the checksums of the ConfigMaps and Secrets
+// referenced by the Nginx differ between the current and desired pod templates.
+func checksumsDiverged(current, desired *corev1.PodTemplateSpec) bool {
+	for _, key := range []string{configChecksumAnnotationKey, tlsChecksumAnnotationKey} {
+		if current.Annotations[key] != desired.Annotations[key] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// podTemplateDiverged returns whether the fields set by the operator on the
+// current pod template differ from the desired one. The fields left unset on
+// the desired pod template are ignored, as they may be defaulted by the API
+// server or set by other controllers (e.g. the kubectl restartedAt
+// annotation).
+func podTemplateDiverged(current, desired *corev1.PodTemplateSpec) bool {
+	desired = desired.DeepCopy()
+	setPodSpecServerDefaults(&desired.Spec)
+
+	return !equality.Semantic.DeepDerivative(*desired, *current)
+}
+
+// setPodSpecServerDefaults sets the integer fields which the API server
+// defaults from their zero values (so they can't be told apart from unset).
+func setPodSpecServerDefaults(spec *corev1.PodSpec) {
+	var containers []*corev1.Container
+	for i := range spec.InitContainers {
+		containers = append(containers, &spec.InitContainers[i])
+	}
+
+	for i := range spec.Containers {
+		containers = append(containers, &spec.Containers[i])
+	}
+
+	for _, c := range containers {
+		for _, probe := range []*corev1.Probe{c.ReadinessProbe, c.LivenessProbe, c.StartupProbe} {
+			if probe == nil {
+				continue
+			}
+
+			if probe.TimeoutSeconds == 0 {
+				probe.TimeoutSeconds = 1
+			}
+
+			if probe.PeriodSeconds == 0 {
+				probe.PeriodSeconds = 10
+			}
+
+			if probe.SuccessThreshold == 0 {
+				probe.SuccessThreshold = 1
+			}
+
+			if probe.FailureThreshold == 0 {
+				probe.FailureThreshold = 3
+			}
+		}
+
+		if !spec.HostNetwork {
+			continue
+		}
+
+		for j := range c.Ports {
+			if c.Ports[j].HostPort == 0 {
+				c.Ports[j].HostPort = c.Ports[j].ContainerPort
+			}
+		}
+	}
+}
+
+func (r *NginxReconciler) reconcileService(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	return r.reconcileServiceObject(ctx, nginx, k8s.NewService(nginx))
+}
+
+func (r *NginxReconciler) reconcileInternalService(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	newService := k8s.NewInternalService(nginx)
+	if nginx.Spec.InternalService != nil {
+		return r.reconcileServiceObject(ctx, nginx, newService)
+	}
+
+	return r.deleteOwnedObject(ctx, nginx, types.NamespacedName{Name: newService.Name, Namespace: newService.Namespace}, &corev1.Service{}, "Service", "service")
+}
+
+func (r *NginxReconciler) reconcileServiceObject(ctx context.Context, nginx *nginxv1alpha1.Nginx, newService *corev1.Service) error {
+	tolerateDrift := nginx.Spec.DriftPolicy == v1alpha1.DriftPolicyTolerate
+	if tolerateDrift {
+		newService.Annotations = mergeMap(newService.Annotations, map[string]string{
+			serviceChecksumAnnotationKey: serviceChecksum(newService),
+		})
+	}
+
+	var currentService corev1.Service
+	err := r.Client.Get(ctx, types.NamespacedName{Name: newService.Name, Namespace: newService.Namespace}, &currentService)
+
+	if errors.IsNotFound(err) {
+		err = r.Client.Create(ctx, newService)
+		if errors.IsForbidden(err) && strings.Contains(err.Error(), "exceeded quota") {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ServiceQuotaExceeded", "failed to create Service: %s", err)
+			return err
+		}
+
+		if err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ServiceCreationFailed", "failed to create Service: %s", err)
+			return err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "ServiceCreated", "service created successfully")
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to retrieve Service resource: %v", err)
+	}
+
+	if tolerateDrift && currentService.Annotations[serviceChecksumAnnotationKey] == newService.Annotations[serviceChecksumAnnotationKey] {
+		return nil
+	}
+
+	if newService.Annotations[gcpNetworkTierAnnotationKey] != currentService.Annotations[gcpNetworkTierAnnotationKey] {
+		// if you want to change network tier, please ask system administrator to manually change/delete the kubernetes service
+		r.EventRecorder.Event(nginx, corev1.EventTypeWarning, "GCPNetworkTierNoChange", "the GCP network tier of this service cannot be changed, because IP address may change and cause downtime")
+		newService.Annotations[gcpNetworkTierAnnotationKey] = currentService.Annotations[gcpNetworkTierAnnotationKey]
+	}
+
+	newService.ResourceVersion = currentService.ResourceVersion
+	newService.Spec.ClusterIP = currentService.Spec.ClusterIP
+	newService.Spec.HealthCheckNodePort = currentService.Spec.HealthCheckNodePort
+	newService.Spec.ClusterIPs = currentService.Spec.ClusterIPs
+
+	if newService.Spec.IPFamilyPolicy == nil {
+		// NOTE: keeping the IP families allocated by the API server when
+		// they aren't set explicitly.
+		newService.Spec.IPFamilyPolicy = currentService.Spec.IPFamilyPolicy
+		if len(newService.Spec.IPFamilies) == 0 {
+			newService.Spec.IPFamilies = currentService.Spec.IPFamilies
+		}
+	}
+	newService.Finalizers = currentService.Finalizers
+
+	for annotation, value := range currentService.Annotations {
+		if newService.Annotations[annotation] == "" {
+			newService.Annotations[annotation] = value
+		}
+	}
+
+	if newService.Spec.Type == corev1.ServiceTypeNodePort || newService.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		// avoid nodeport reallocation preserving the current ones
+		for _, currentPort := range currentService.Spec.Ports {
+			for index, newPort := range newService.Spec.Ports {
+				if currentPort.Port == newPort.Port {
+					newService.Spec.Ports[index].NodePort = currentPort.NodePort
+				}
+			}
+		}
+	}
+
+	if !shouldUpdateService(&currentService, newService) {
+		return nil
+	}
+
+	err = r.Client.Update(ctx, newService)
+	if err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ServiceUpdateFailed", "failed to update Service: %s", err)
+		return err
+	}
+
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "ServiceUpdated", "service updated successfully")
+	return nil
+}
+
+func (r *NginxReconciler) reconcileHeadlessService(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	newService := k8s.NewHeadlessService(nginx)
+	key := types.NamespacedName{Name: newService.Name, Namespace: newService.Namespace}
+
+	if nginx.Spec.Service == nil || !nginx.Spec.Service.Headless {
+		return r.deleteOwnedObject(ctx, nginx, key, &corev1.Service{}, "HeadlessService", "headless service")
+	}
+
+	var currentService corev1.Service
+	err := r.Client.Get(ctx, key, &currentService)
+	if errors.IsNotFound(err) {
+		if err = r.Client.Create(ctx, newService); err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "HeadlessServiceCreationFailed", "failed to create headless Service: %s", err)
+			return err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "HeadlessServiceCreated", "headless service created successfully")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !metav1.IsControlledBy(&currentService, nginx) {
+		return fmt.Errorf("Service %q already exists and is not managed by this Nginx", currentService.Name)
+	}
+
+	if labels.Equals(currentService.Labels, newService.Labels) &&
+		labels.Equals(currentService.Spec.Selector, newService.Spec.Selector) &&
+		reflect.DeepEqual(currentService.Spec.Ports, newService.Spec.Ports) {
+		return nil
+	}
+
+	currentService.Labels = newService.Labels
+	currentService.Spec.Selector = newService.Spec.Selector
+	currentService.Spec.Ports = newService.Spec.Ports
+
+	if err = r.Client.Update(ctx, &currentService); err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "HeadlessServiceUpdateFailed", "failed to update headless Service: %s", err)
+		return err
+	}
+
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "HeadlessServiceUpdated", "headless service updated successfully")
+	return nil
+}
+
+// canAdopt returns an error unless the object, not controlled by the Nginx,
+// can be adopted by it: the adoption must be requested and the object must not
+// be controlled by anything else.
+func canAdopt(nginx *nginxv1alpha1.Nginx, obj metav1.Object, kind string) error {
+	if nginx.Annotations[adoptAnnotationKey] != "true" {
+		return fmt.Errorf("%s %q already exists and is not managed by this Nginx (set the %q annotation to adopt it)", kind, obj.GetName(), adoptAnnotationKey)
+	}
+
+	if owner := metav1.GetControllerOf(obj); owner != nil {
+		return fmt.Errorf("cannot adopt %s %q: it's controlled by %s %q", kind, obj.GetName(), owner.Kind, owner.Name)
+	}
+
+	return nil
+}
+
+// serviceChecksum returns a digest of the labels, annotations and spec of the
+// desired Service.
+func serviceChecksum(s *corev1.Service) string {
+	data, _ := json.Marshal(struct {
+		Labels      map[string]string
+		Annotations map[string]string
+		Spec        corev1.ServiceSpec
+	}{s.Labels, s.Annotations, s.Spec})
+
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+func shouldUpdateService(currentService, newService *corev1.Service) bool {
+	if currentService == nil || newService == nil {
+		return false
+	}
+
+	return !labels.Equals(currentService.Labels, newService.Labels) ||
+		!labels.Equals(currentService.Annotations, newService.Annotations) ||
+		!reflect.DeepEqual(currentService.Finalizers, newService.Finalizers) ||
+		currentService.Spec.Type != newService.Spec.Type ||
+		currentService.Spec.LoadBalancerIP != newService.Spec.LoadBalancerIP ||
+		!reflect.DeepEqual(currentService.Spec.LoadBalancerSourceRanges, newService.Spec.LoadBalancerSourceRanges) ||
+		externalTrafficPolicy(currentService) != externalTrafficPolicy(newService) ||
+		sessionAffinity(currentService) != sessionAffinity(newService) ||
+		(newService.Spec.SessionAffinityConfig != nil && !reflect.DeepEqual(currentService.Spec.SessionAffinityConfig, newService.Spec.SessionAffinityConfig)) ||
+		(len(newService.Spec.IPFamilies) > 0 && !reflect.DeepEqual(currentService.Spec.IPFamilies, newService.Spec.IPFamilies)) ||
+		(newService.Spec.IPFamilyPolicy != nil && !reflect.DeepEqual(currentService.Spec.IPFamilyPolicy, newService.Spec.IPFamilyPolicy)) ||
+		!labels.Equals(currentService.Spec.Selector, newService.Spec.Selector) ||
+		!reflect.DeepEqual(currentService.Spec.Ports, newService.Spec.Ports)
+}
+
+// externalTrafficPolicy returns the external traffic policy of the Service as
+// defaulted by the API server, "Cluster" on NodePort and LoadBalancer ones.
+func externalTrafficPolicy(s *corev1.Service) corev1.ServiceExternalTrafficPolicyType {
+	if s.Spec.ExternalTrafficPolicy == "" && (s.Spec.Type == corev1.ServiceTypeNodePort || s.Spec.Type == corev1.ServiceTypeLoadBalancer) {
+		return corev1.ServiceExternalTrafficPolicyTypeCluster
+	}
+	return s.Spec.ExternalTrafficPolicy
+}
+
+func sessionAffinity(s *corev1.Service) corev1.ServiceAffinity {
+	if s.Spec.SessionAffinity == "" {
+		return corev1.ServiceAffinityNone
+	}
+	return s.Spec.SessionAffinity
+}
+
+func (r *NginxReconciler) reconcileIngress(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	if nginx == nil {
+		return fmt.Errorf("nginx cannot be nil")
+	}
+
+	newIngress := k8s.NewIngress(nginx)
+
+	var currentIngress networkingv1.Ingress
+	err := r.Client.Get(ctx, types.NamespacedName{Name: newIngress.Name, Namespace: newIngress.Namespace}, &currentIngress)
+	if errors.IsNotFound(err) {
+		if nginx.Spec.Ingress == nil {
+			return nil
+		}
+
+		if err = r.Client.Create(ctx, newIngress); err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "IngressCreationFailed", "failed to create Ingress: %s", err)
+			return err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "IngressCreated", "ingress created successfully")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if nginx.Spec.Ingress == nil {
+		if err = r.Client.Delete(ctx, &currentIngress); err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "IngressDeletionFailed", "failed to delete Ingress: %s", err)
+			return err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "IngressDeleted", "ingress deleted successfully")
+		return nil
+	}
+
+	preserveACMEChallengePaths(&currentIngress, newIngress)
+
+	if !shouldUpdateIngress(&currentIngress, newIngress) {
+		return nil
+	}
+
+	for key, value := range currentIngress.Annotations {
+		if newIngress.Annotations[key] == "" {
+			newIngress.Annotations[key] = value
+		}
+	}
+
+	newIngress.ResourceVersion = currentIngress.ResourceVersion
+	newIngress.Finalizers = currentIngress.Finalizers
+
+	if err = r.Client.Update(ctx, newIngress); err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "IngressUpdateFailed", "failed to update Ingress: %s", err)
+		return err
+	}
+
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "IngressUpdated", "ingress updated successfully")
+	return nil
+}
+
+// preserveACMEChallengePaths copies the HTTP-01 challenge paths, which
+// cert-manager adds to the Ingress when its solver edits it in place, from the
+// current Ingress to the new one. Otherwise the operator would remove them
+// before the certificate gets issued.
+func preserveACMEChallengePaths(currentIngress, newIngress *networkingv1.Ingress) {
+	for _, currentRule := range currentIngress.Spec.Rules {
+		if currentRule.HTTP == nil {
+			continue
+		}
+
+		var challengePaths []networkingv1.HTTPIngressPath
+		for _, p := range currentRule.HTTP.Paths {
+			if strings.HasPrefix(p.Path, acmeChallengePathPrefix) {
+				challengePaths = append(challengePaths, p)
+			}
+		}
+
+		if len(challengePaths) == 0 {
+			continue
+		}
+
+		index := -1
+		for i, rule := range newIngress.Spec.Rules {
+			if rule.Host == currentRule.Host {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			newIngress.Spec.Rules = append(newIngress.Spec.Rules, networkingv1.IngressRule{Host: currentRule.Host})
+			index = len(newIngress.Spec.Rules) - 1
+		}
+
+		rule := &newIngress.Spec.Rules[index]
+		if rule.HTTP == nil {
+			rule.HTTP = &networkingv1.HTTPIngressRuleValue{}
+		}
+
+		// NOTE: the challenge paths come first, so they aren't shadowed by
+		// the prefix paths on ingress controllers which honor the order.
+		rule.HTTP.Paths = append(challengePaths, rule.HTTP.Paths...)
+	}
+}
+
+func shouldUpdateIngress(currentIngress, newIngress *networkingv1.Ingress) bool {
+	if currentIngress == nil || newIngress == nil {
+		return false
+	}
+
+	for key, value := range newIngress.Annotations {
+		if currentIngress.Annotations[key] != value {
+			return true
+		}
+	}
+
+	return !reflect.DeepEqual(currentIngress.Labels, newIngress.Labels) ||
+		!reflect.DeepEqual(currentIngress.Spec, newIngress.Spec)
+}
+
+func (r *NginxReconciler) reconcileHorizontalPodAutoscaler(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	newHPA := k8s.NewHorizontalPodAutoscaler(nginx)
+	key := types.NamespacedName{Name: newHPA.Name, Namespace: newHPA.Namespace}
+
+	if nginx.Spec.Autoscaling == nil {
+		// NOTE: HorizontalPodAutoscalers created by users, e.g. targeting the
+		// Nginx scale subresource with the same name, are kept.
+		return r.deleteOwnedObject(ctx, nginx, key, &autoscalingv2.HorizontalPodAutoscaler{}, "HorizontalPodAutoscaler", "horizontal pod autoscaler")
+	}
+
+	var currentHPA autoscalingv2.HorizontalPodAutoscaler
+	err := r.Client.Get(ctx, key, &currentHPA)
+	if errors.IsNotFound(err) {
+		if err = r.Client.Create(ctx, newHPA); err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "HorizontalPodAutoscalerCreationFailed", "failed to create HorizontalPodAutoscaler: %s", err)
+			return err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "HorizontalPodAutoscalerCreated", "horizontal pod autoscaler created successfully")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !metav1.IsControlledBy(&currentHPA, nginx) {
+		return fmt.Errorf("HorizontalPodAutoscaler %q already exists and is not managed by this Nginx", currentHPA.Name)
+	}
+
+	if reflect.DeepEqual(currentHPA.Spec.ScaleTargetRef, newHPA.Spec.ScaleTargetRef) &&
+		reflect.DeepEqual(currentHPA.Spec.MinReplicas, newHPA.Spec.MinReplicas) &&
+		currentHPA.Spec.MaxReplicas == newHPA.Spec.MaxReplicas &&
+		reflect.DeepEqual(currentHPA.Spec.Metrics, newHPA.Spec.Metrics) {
+		return nil
+	}
+
+	currentHPA.Spec.ScaleTargetRef = newHPA.Spec.ScaleTargetRef
+	currentHPA.Spec.MinReplicas = newHPA.Spec.MinReplicas
+	currentHPA.Spec.MaxReplicas = newHPA.Spec.MaxReplicas
+	currentHPA.Spec.Metrics = newHPA.Spec.Metrics
+
+	if err = r.Client.Update(ctx, &currentHPA); err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "HorizontalPodAutoscalerUpdateFailed", "failed to update HorizontalPodAutoscaler: %s", err)
+		return err
+	}
+
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "HorizontalPodAutoscalerUpdated", "horizontal pod autoscaler updated successfully")
+	return nil
+}
+
+func (r *NginxReconciler) reconcileServiceAccount(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	newSA := k8s.NewServiceAccount(nginx)
+	key := types.NamespacedName{Name: newSA.Name, Namespace: newSA.Namespace}
+
+	if nginx.Spec.ServiceAccount == nil {
+		// NOTE: ServiceAccount not created by the operator, e.g. the one
+		// referenced on pod template, must be left untouched.
+		return r.deleteOwnedObject(ctx, nginx, key, &corev1.ServiceAccount{}, "ServiceAccount", "service account")
+	}
+
+	var currentSA corev1.ServiceAccount
+	err := r.Client.Get(ctx, key, &currentSA)
+	if errors.IsNotFound(err) {
+		if err = r.Client.Create(ctx, newSA); err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ServiceAccountCreationFailed", "failed to create ServiceAccount: %s", err)
+			return err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "ServiceAccountCreated", "service account created successfully")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !metav1.IsControlledBy(&currentSA, nginx) {
+		return fmt.Errorf("ServiceAccount %q already exists and is not managed by this Nginx", currentSA.Name)
+	}
+
+	if !shouldUpdateServiceAccount(&currentSA, newSA) {
+		return nil
+	}
+
+	currentSA.Labels = mergeMap(currentSA.Labels, newSA.Labels)
+	currentSA.Annotations = mergeMap(currentSA.Annotations, newSA.Annotations)
+	currentSA.ImagePullSecrets = newSA.ImagePullSecrets
+
+	if err = r.Client.Update(ctx, &currentSA); err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ServiceAccountUpdateFailed", "failed to update ServiceAccount: %s", err)
+		return err
+	}
+
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "ServiceAccountUpdated", "service account updated successfully")
+	return nil
+}
+
+func shouldUpdateServiceAccount(currentSA, newSA *corev1.ServiceAccount) bool {
+	for key, value := range newSA.Labels {
+		if currentSA.Labels[key] != value {
+			return true
+		}
+	}
+
+	for key, value := range newSA.Annotations {
+		if currentSA.Annotations[key] != value {
+			return true
+		}
+	}
+
+	return !reflect.DeepEqual(currentSA.ImagePullSecrets, newSA.ImagePullSecrets)
+}
+
+func mergeMap(a, b map[string]string) map[string]string {
+	if len(b) == 0 {
+		return a
+	}
+
+	merged := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		merged[k] = v
+	}
+
+	for k, v := range b {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+func (r *NginxReconciler) reconcilePodDisruptionBudget(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	newPDB := k8s.NewPodDisruptionBudget(nginx)
+	key := types.NamespacedName{Name: newPDB.Name, Namespace: newPDB.Namespace}
+
+	if nginx.Spec.PodDisruptionBudget == nil {
+		return r.deleteOwnedObject(ctx, nginx, key, &policyv1.PodDisruptionBudget{}, "PodDisruptionBudget", "pod disruption budget")
+	}
+
+	var currentPDB policyv1.PodDisruptionBudget
+	err := r.Client.Get(ctx, key, &currentPDB)
+	if errors.IsNotFound(err) {
+		if err = r.Client.Create(ctx, newPDB); err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "PodDisruptionBudgetCreationFailed", "failed to create PodDisruptionBudget: %s", err)
+			return err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "PodDisruptionBudgetCreated", "pod disruption budget created successfully")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !metav1.IsControlledBy(&currentPDB, nginx) {
+		return fmt.Errorf("PodDisruptionBudget %q already exists and is not managed by this Nginx", currentPDB.Name)
+	}
+
+	if reflect.DeepEqual(currentPDB.Spec.Selector, newPDB.Spec.Selector) &&
+		reflect.DeepEqual(currentPDB.Spec.MinAvailable, newPDB.Spec.MinAvailable) &&
+		reflect.DeepEqual(currentPDB.Spec.MaxUnavailable, newPDB.Spec.MaxUnavailable) {
+		return nil
+	}
+
+	currentPDB.Spec.Selector = newPDB.Spec.Selector
+	currentPDB.Spec.MinAvailable = newPDB.Spec.MinAvailable
+	currentPDB.Spec.MaxUnavailable = newPDB.Spec.MaxUnavailable
+
+	if err = r.Client.Update(ctx, &currentPDB); err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "PodDisruptionBudgetUpdateFailed", "failed to update PodDisruptionBudget: %s", err)
+		return err
+	}
+
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "PodDisruptionBudgetUpdated", "pod disruption budget updated successfully")
+	return nil
+}
+
+func (r *NginxReconciler) reconcileNetworkPolicy(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	newNP := k8s.NewNetworkPolicy(nginx)
+	key := types.NamespacedName{Name: newNP.Name, Namespace: newNP.Namespace}
+
+	if nginx.Spec.NetworkPolicy == nil {
+		// NOTE: deleting a NetworkPolicy of the user would let in the traffic
+		// it was meant to block.
+		return r.deleteOwnedObject(ctx, nginx, key, &networkingv1.NetworkPolicy{}, "NetworkPolicy", "network policy")
+	}
+
+	var currentNP networkingv1.NetworkPolicy
+	err := r.Client.Get(ctx, key, &currentNP)
+	if errors.IsNotFound(err) {
+		if err = r.Client.Create(ctx, newNP); err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "NetworkPolicyCreationFailed", "failed to create NetworkPolicy: %s", err)
+			return err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "NetworkPolicyCreated", "network policy created successfully")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !metav1.IsControlledBy(&currentNP, nginx) {
+		return fmt.Errorf("NetworkPolicy %q already exists and is not managed by this Nginx", currentNP.Name)
+	}
+
+	if reflect.DeepEqual(currentNP.Spec, newNP.Spec) {
+		return nil
+	}
+
+	currentNP.Spec = newNP.Spec
+
+	if err = r.Client.Update(ctx, &currentNP); err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "NetworkPolicyUpdateFailed", "failed to update NetworkPolicy: %s", err)
+		return err
+	}
+
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "NetworkPolicyUpdated", "network policy updated successfully")
+	return nil
+}
+
+func (r *NginxReconciler) reconcileHTTPRoute(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	newRoute := k8s.NewHTTPRoute(nginx)
+	key := types.NamespacedName{Name: newRoute.GetName(), Namespace: newRoute.GetNamespace()}
+
+	currentRoute := &unstructured.Unstructured{}
+	currentRoute.SetGroupVersionKind(k8s.HTTPRouteGroupVersionKind)
+
+	if nginx.Spec.Gateway == nil {
+		return r.deleteOwnedObject(ctx, nginx, key, currentRoute, "HTTPRoute", "http route")
+	}
+
+	err := r.Client.Get(ctx, key, currentRoute)
+	if errors.IsNotFound(err) {
+		if err = r.Client.Create(ctx, newRoute); err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "HTTPRouteCreationFailed", "failed to create HTTPRoute: %s", err)
+			return err
+		}
+
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "HTTPRouteCreated", "http route created successfully")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !metav1.IsControlledBy(currentRoute, nginx) {
+		return fmt.Errorf("HTTPRoute %q already exists and is not managed by this Nginx", currentRoute.GetName())
+	}
+
+	if !shouldUpdateHTTPRoute(currentRoute, newRoute) {
+		return nil
+	}
+
+	annotations := newRoute.GetAnnotations()
+	for key, value := range currentRoute.GetAnnotations() {
+		if annotations[key] == "" {
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[key] = value
+		}
+	}
+
+	newRoute.SetAnnotations(annotations)
+	newRoute.SetResourceVersion(currentRoute.GetResourceVersion())
+	newRoute.SetFinalizers(currentRoute.GetFinalizers())
+
+	if err = r.Client.Update(ctx, newRoute); err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "HTTPRouteUpdateFailed", "failed to update HTTPRoute: %s", err)
+		return err
+	}
+
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "HTTPRouteUpdated", "http route updated successfully")
+	return nil
+}
+
+func shouldUpdateHTTPRoute(currentRoute, newRoute *unstructured.Unstructured) bool {
+	if currentRoute == nil || newRoute == nil {
+		return false
+	}
+
+	for key, value := range newRoute.GetAnnotations() {
+		if currentRoute.GetAnnotations()[key] != value {
+			return true
+		}
+	}
+
+	currentHostnames, _, _ := unstructured.NestedStringSlice(currentRoute.Object, "spec", "hostnames")
+	newHostnames, _, _ := unstructured.NestedStringSlice(newRoute.Object, "spec", "hostnames")
+
+	// NOTE: the fields defaulted by the API server, e.g. the parentRefs
+	// group and kind, are only on the current HTTPRoute spec. The hostnames
+	// are compared apart since they're omitted from the desired spec when
+	// there are none.
+	return !labels.Equals(currentRoute.GetLabels(), newRoute.GetLabels()) ||
+		!reflect.DeepEqual(currentHostnames, newHostnames) ||
+		!unstructuredDerivative(newRoute.Object["spec"], currentRoute.Object["spec"])
+}
+
+// unstructuredDerivative returns whether the current value holds the desired
+// one, ignoring the map keys only set on the current value. Unlike
+// equality.Semantic.DeepDerivative, lists must have the same length, so an
+// item removed from the desired value is told apart.
+func unstructuredDerivative(desired, current interface{}) bool {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		c, _ := current.(map[string]interface{})
+		for key, value := range d {
+			if !unstructuredDerivative(value, c[key]) {
+				return false
+			}
+		}
+
+		return true
+
+	case []interface{}:
+		c, _ := current.([]interface{})
+		if len(c) != len(d) {
+			return false
+		}
+
+		for i := range d {
+			if !unstructuredDerivative(d[i], c[i]) {
+				return false
+			}
+		}
+
+		return true
+
+	default:
+		return reflect.DeepEqual(desired, current)
+	}
 }
 
-func (r *NginxReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("nginx", req.NamespacedName)
+func (r *NginxReconciler) reconcileServiceMonitor(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	newSM := k8s.NewServiceMonitor(nginx)
+	key := types.NamespacedName{Name: newSM.GetName(), Namespace: newSM.GetNamespace()}
 
-	var instance nginxv1alpha1.Nginx
-	err := r.Client.Get(ctx, req.NamespacedName, &instance)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			log.Info("Nginx resource not found, skipping reconcile")
-			return ctrl.Result{}, nil
+	currentSM := &unstructured.Unstructured{}
+	currentSM.SetGroupVersionKind(k8s.ServiceMonitorGroupVersionKind)
+
+	if nginx.Spec.Metrics == nil || !nginx.Spec.Metrics.Enabled {
+		return r.deleteOwnedObject(ctx, nginx, key, currentSM, "ServiceMonitor", "service monitor")
+	}
+
+	err := r.Client.Get(ctx, key, currentSM)
+	if errors.IsNotFound(err) {
+		if err = r.Client.Create(ctx, newSM); err != nil {
+			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ServiceMonitorCreationFailed", "failed to create ServiceMonitor: %s", err)
+			return err
 		}
 
-		log.Error(err, "Unable to get Nginx resource")
-		return ctrl.Result{}, err
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "ServiceMonitorCreated", "service monitor created successfully")
+		return nil
 	}
 
-	if !r.shouldManageNginx(&instance) {
-		log.V(1).Info("Nginx resource doesn't match annotations filters, skipping it")
-		return ctrl.Result{Requeue: true, RequeueAfter: 5 * time.Minute}, nil
+	if err != nil {
+		return err
 	}
 
-	if err := r.reconcileNginx(ctx, &instance); err != nil {
-		log.Error(err, "Fail to reconcile")
-		return ctrl.Result{}, err
+	if !metav1.IsControlledBy(currentSM, nginx) {
+		return fmt.Errorf("ServiceMonitor %q already exists and is not managed by this Nginx", currentSM.GetName())
 	}
 
-	if err := r.refreshStatus(ctx, &instance); err != nil {
-		log.Error(err, "Fail to refresh status subresource")
-		return ctrl.Result{}, err
+	if labels.Equals(currentSM.GetLabels(), newSM.GetLabels()) && reflect.DeepEqual(currentSM.Object["spec"], newSM.Object["spec"]) {
+		return nil
 	}
 
-	return ctrl.Result{}, nil
-}
+	newSM.SetAnnotations(currentSM.GetAnnotations())
+	newSM.SetResourceVersion(currentSM.GetResourceVersion())
+	newSM.SetFinalizers(currentSM.GetFinalizers())
 
-func (r *NginxReconciler) reconcileNginx(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
-	if err := r.reconcileDeployment(ctx, nginx); err != nil {
+	if err = r.Client.Update(ctx, newSM); err != nil {
+		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ServiceMonitorUpdateFailed", "failed to update ServiceMonitor: %s", err)
 		return err
 	}
 
-	if err := r.reconcileService(ctx, nginx); err != nil {
+	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "ServiceMonitorUpdated", "service monitor updated successfully")
+	return nil
+}
+
+func (r *NginxReconciler) refreshStatus(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
+	deploys, err := listDeployments(ctx, r.Client, nginx)
+	if err != nil {
 		return err
 	}
 
-	if err := r.reconcileIngress(ctx, nginx); err != nil {
-		return err
+	var deployStatuses []v1alpha1.DeploymentStatus
+	var replicas, readyReplicas, updatedReplicas, desiredReplicas int32
+	for _, d := range deploys {
+		replicas += d.Status.Replicas
+		readyReplicas += d.Status.ReadyReplicas
+		updatedReplicas += d.Status.UpdatedReplicas
+		if d.Spec.Replicas != nil {
+			desiredReplicas += *d.Spec.Replicas
+		}
+		deployStatuses = append(deployStatuses, v1alpha1.DeploymentStatus{Name: d.Name})
 	}
 
-	return nil
-}
+	var daemonSet *appsv1.DaemonSet
+	if nginx.Spec.DeploymentMode == v1alpha1.DeploymentModeDaemonSet {
+		var ds appsv1.DaemonSet
+		err = r.Client.Get(ctx, types.NamespacedName{Name: nginx.Name, Namespace: nginx.Namespace}, &ds)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to retrieve DaemonSet: %w", err)
+		}
 
-func (r *NginxReconciler) reconcileDeployment(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
-	newDeploy, err := k8s.NewDeployment(nginx)
-	if err != nil {
-		return fmt.Errorf("failed to build Deployment from Nginx: %w", err)
+		if err == nil {
+			daemonSet = &ds
+			replicas, readyReplicas, updatedReplicas, desiredReplicas = ds.Status.CurrentNumberScheduled, ds.Status.NumberReady, ds.Status.UpdatedNumberScheduled, ds.Status.DesiredNumberScheduled
+		}
 	}
 
-	var currentDeploy appsv1.Deployment
-	err = r.Client.Get(ctx, types.NamespacedName{Name: newDeploy.Name, Namespace: newDeploy.Namespace}, &currentDeploy)
-	if errors.IsNotFound(err) {
-		return r.Client.Create(ctx, newDeploy)
+	services, err := listServices(ctx, r.Client, nginx)
+	if err != nil {
+		return fmt.Errorf("failed to list services for nginx: %v", err)
 	}
 
+	ingresses, err := listIngresses(ctx, r.Client, nginx)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve Deployment: %w", err)
+		return fmt.Errorf("failed to list ingresses for nginx: %w", err)
 	}
 
-	existingNginxSpec, err := k8s.ExtractNginxSpec(currentDeploy.ObjectMeta)
+	sort.Slice(nginx.Status.Services, func(i, j int) bool {
+		return nginx.Status.Services[i].Name < nginx.Status.Services[j].Name
+	})
+
+	sort.Slice(nginx.Status.Ingresses, func(i, j int) bool {
+		return nginx.Status.Ingresses[i].Name < nginx.Status.Ingresses[j].Name
+	})
+
+	pods, err := listPods(ctx, r.Client, nginx)
 	if err != nil {
-		return fmt.Errorf("failed to extract Nginx spec from Deployment annotations: %w", err)
+		return fmt.Errorf("failed to list pods for nginx: %w", err)
 	}
 
-	if reflect.DeepEqual(nginx.Spec, existingNginxSpec) {
-		return nil
+	status := v1alpha1.NginxStatus{
+		ObservedGeneration: nginx.Status.ObservedGeneration,
+		CurrentReplicas:    replicas,
+		ReadyReplicas:      readyReplicas,
+		UpdatedReplicas:    updatedReplicas,
+		DesiredReplicas:    desiredReplicas,
+		PodSelector:        k8s.LabelsForNginxString(nginx.Name),
+		Deployments:        deployStatuses,
+		Services:           services,
+		Ingresses:          ingresses,
+		Pods:               podStatuses(pods),
+		Conditions:         slices.Clone(nginx.Status.Conditions),
 	}
 
-	replicas := currentDeploy.Spec.Replicas
+	// NOTE: changes on a paused Nginx are not applied, so the generation
+	// must not be reported as observed.
+	if !isNginxPaused(nginx) {
+		status.ObservedGeneration = nginx.Generation
+	}
 
-	patch := client.StrategicMergeFrom(currentDeploy.DeepCopy())
-	currentDeploy.Spec = newDeploy.Spec
+	if nginx.Spec.DeploymentMode == v1alpha1.DeploymentModeDaemonSet {
+		setDaemonSetStatusConditions(&status, nginx, daemonSet, pods)
+	} else {
+		deploy := findDeployment(deploys, nginx.Name)
+		setStatusConditions(&status, nginx, deploy, pods)
 
-	if newDeploy.Spec.Replicas == nil {
-		// NOTE: replicas field is set to nil whenever it's managed by some
-		// autoscaler controller e.g HPA.
-		currentDeploy.Spec.Replicas = replicas
+		if deploy != nil && deploy.Annotations[upgradeToVersionAnnotationKey] != "" {
+			status.Upgrade = &v1alpha1.NginxUpgradeStatus{
+				FromVersion: deploy.Annotations[upgradeFromVersionAnnotationKey],
+				ToVersion:   deploy.Annotations[upgradeToVersionAnnotationKey],
+			}
+		}
 	}
 
-	err = k8s.SetNginxSpec(&currentDeploy.ObjectMeta, nginx.Spec)
-	if err != nil {
-		return fmt.Errorf("failed to set Nginx spec in Deployment annotations: %w", err)
+	if reflect.DeepEqual(nginx.Status, status) {
+		return nil
 	}
 
-	err = r.Client.Patch(ctx, &currentDeploy, patch)
+	previous := meta.FindStatusCondition(nginx.Status.Conditions, v1alpha1.NginxConditionDegraded)
+	if degraded := meta.FindStatusCondition(status.Conditions, v1alpha1.NginxConditionDegraded); degraded != nil && degraded.Status == metav1.ConditionTrue &&
+		(previous == nil || previous.Status != degraded.Status || previous.Reason != degraded.Reason) {
+		// NOTE: warning only once per failure, not on every status refresh.
+		r.EventRecorder.Event(nginx, corev1.EventTypeWarning, degraded.Reason, degraded.Message)
+	}
+
+	// NOTE: merge patches on the status subresource (without the resource
+	// version) neither conflict with nor overwrite concurrent spec changes.
+	patch := client.MergeFrom(nginx.DeepCopy())
+	nginx.Status = status
+
+	err = r.Client.Status().Patch(ctx, nginx, patch)
 	if err != nil {
-		return fmt.Errorf("failed to patch Deployment: %w", err)
+		return fmt.Errorf("failed to update nginx status: %v", err)
 	}
 
 	return nil
 }
 
-func (r *NginxReconciler) reconcileService(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
-	newService := k8s.NewService(nginx)
+var podFailureReasons = []string{
+	"CrashLoopBackOff",
+	"CreateContainerConfigError",
+	"CreateContainerError",
+	"ErrImagePull",
+	"ImagePullBackOff",
+	"InvalidImageName",
+}
 
-	var currentService corev1.Service
-	err := r.Client.Get(ctx, types.NamespacedName{Name: newService.Name, Namespace: newService.Namespace}, &currentService)
+func setStatusConditions(status *v1alpha1.NginxStatus, nginx *nginxv1alpha1.Nginx, deploy *appsv1.Deployment, pods []corev1.Pod) {
+	if deploy == nil {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               v1alpha1.NginxConditionAvailable,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: nginx.Generation,
+			Reason:             "DeploymentNotFound",
+			Message:            "Deployment not found",
+		})
+		return
+	}
 
-	if errors.IsNotFound(err) {
-		err = r.Client.Create(ctx, newService)
-		if errors.IsForbidden(err) && strings.Contains(err.Error(), "exceeded quota") {
-			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ServiceQuotaExceeded", "failed to create Service: %s", err)
-			return err
-		}
+	available := metav1.Condition{
+		Type:               v1alpha1.NginxConditionAvailable,
+		Status:             metav1.ConditionUnknown,
+		ObservedGeneration: nginx.Generation,
+		Reason:             "Unknown",
+	}
 
-		if err != nil {
-			r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ServiceCreationFailed", "failed to create Service: %s", err)
-			return err
-		}
+	if c := deploymentCondition(deploy, appsv1.DeploymentAvailable); c != nil {
+		available.Status = metav1.ConditionStatus(c.Status)
+		available.Reason = c.Reason
+		available.Message = c.Message
+	}
 
-		r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "ServiceCreated", "service created successfully")
-		return nil
+	meta.SetStatusCondition(&status.Conditions, available)
+
+	progressing := metav1.Condition{
+		Type:               v1alpha1.NginxConditionProgressing,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: nginx.Generation,
+		Reason:             "RolloutComplete",
+		Message:            "All pods are updated and ready",
 	}
 
-	if err != nil {
-		return fmt.Errorf("failed to retrieve Service resource: %v", err)
+	var desired int32 = 1
+	if deploy.Spec.Replicas != nil {
+		desired = *deploy.Spec.Replicas
 	}
 
-	if newService.Annotations[gcpNetworkTierAnnotationKey] != currentService.Annotations[gcpNetworkTierAnnotationKey] {
-		// if you want to change network tier, please ask system administrator to manually change/delete the kubernetes service
-		r.EventRecorder.Event(nginx, corev1.EventTypeWarning, "GCPNetworkTierNoChange", "the GCP network tier of this service cannot be changed, because IP address may change and cause downtime")
-		newService.Annotations[gcpNetworkTierAnnotationKey] = currentService.Annotations[gcpNetworkTierAnnotationKey]
+	if deploymentRollingOut(deploy) {
+		progressing.Status = metav1.ConditionTrue
+		progressing.Reason = "RolloutInProgress"
+		progressing.Message = fmt.Sprintf("%d of %d pods are updated and %d are ready", deploy.Status.UpdatedReplicas, desired, deploy.Status.ReadyReplicas)
 	}
 
-	newService.ResourceVersion = currentService.ResourceVersion
-	newService.Spec.ClusterIP = currentService.Spec.ClusterIP
-	newService.Spec.HealthCheckNodePort = currentService.Spec.HealthCheckNodePort
-	newService.Finalizers = currentService.Finalizers
+	degraded := metav1.Condition{
+		Type:               v1alpha1.NginxConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: nginx.Generation,
+		Reason:             "AsExpected",
+	}
 
-	for annotation, value := range currentService.Annotations {
-		if newService.Annotations[annotation] == "" {
-			newService.Annotations[annotation] = value
-		}
+	if c := deploymentCondition(deploy, appsv1.DeploymentProgressing); c != nil && c.Status == corev1.ConditionFalse {
+		progressing.Status = metav1.ConditionFalse
+		progressing.Reason = c.Reason
+		progressing.Message = c.Message
+
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = c.Reason
+		degraded.Message = c.Message
 	}
 
-	if newService.Spec.Type == corev1.ServiceTypeNodePort || newService.Spec.Type == corev1.ServiceTypeLoadBalancer {
-		// avoid nodeport reallocation preserving the current ones
-		for _, currentPort := range currentService.Spec.Ports {
-			for index, newPort := range newService.Spec.Ports {
-				if currentPort.Port == newPort.Port {
-					newService.Spec.Ports[index].NodePort = currentPort.NodePort
-				}
-			}
-		}
+	if c := deploymentCondition(deploy, appsv1.DeploymentReplicaFailure); c != nil && c.Status == corev1.ConditionTrue {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = c.Reason
+		degraded.Message = c.Message
 	}
 
-	err = r.Client.Update(ctx, newService)
-	if err != nil {
-		r.EventRecorder.Eventf(nginx, corev1.EventTypeWarning, "ServiceUpdateFailed", "failed to update Service: %s", err)
-		return err
+	if reason, message := podFailure(pods); reason != "" {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = reason
+		degraded.Message = message
 	}
 
-	r.EventRecorder.Eventf(nginx, corev1.EventTypeNormal, "ServiceUpdated", "service updated successfully")
-	return nil
+	meta.SetStatusCondition(&status.Conditions, progressing)
+	meta.SetStatusCondition(&status.Conditions, degraded)
 }
 
-func (r *NginxReconciler) reconcileIngress(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
-	if nginx == nil {
-		return fmt.Errorf("nginx cannot be nil")
+// setDaemonSetStatusConditions is the counterpart of setStatusConditions for
+// Nginxes in DaemonSet mode, as DaemonSets don't report conditions themselves.
+func setDaemonSetStatusConditions(status *v1alpha1.NginxStatus, nginx *nginxv1alpha1.Nginx, ds *appsv1.DaemonSet, pods []corev1.Pod) {
+	if ds == nil {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               v1alpha1.NginxConditionAvailable,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: nginx.Generation,
+			Reason:             "DaemonSetNotFound",
+			Message:            "DaemonSet not found",
+		})
+		return
 	}
 
-	newIngress := k8s.NewIngress(nginx)
+	available := metav1.Condition{
+		Type:               v1alpha1.NginxConditionAvailable,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: nginx.Generation,
+		Reason:             "MinimumReplicasAvailable",
+		Message:            fmt.Sprintf("%d of %d pods are available", ds.Status.NumberAvailable, ds.Status.DesiredNumberScheduled),
+	}
 
-	var currentIngress networkingv1.Ingress
-	err := r.Client.Get(ctx, types.NamespacedName{Name: newIngress.Name, Namespace: newIngress.Namespace}, &currentIngress)
-	if errors.IsNotFound(err) {
-		if nginx.Spec.Ingress == nil {
-			return nil
-		}
+	if ds.Status.DesiredNumberScheduled > 0 && ds.Status.NumberAvailable == 0 {
+		available.Status = metav1.ConditionFalse
+		available.Reason = "MinimumReplicasUnavailable"
+	}
 
-		return r.Client.Create(ctx, newIngress)
+	progressing := metav1.Condition{
+		Type:               v1alpha1.NginxConditionProgressing,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: nginx.Generation,
+		Reason:             "RolloutComplete",
+		Message:            "All pods are updated and ready",
 	}
 
-	if err != nil {
-		return err
+	desired := ds.Status.DesiredNumberScheduled
+	if ds.Status.ObservedGeneration < ds.Generation ||
+		ds.Status.UpdatedNumberScheduled < desired ||
+		ds.Status.NumberReady < desired {
+		progressing.Status = metav1.ConditionTrue
+		progressing.Reason = "RolloutInProgress"
+		progressing.Message = fmt.Sprintf("%d of %d pods are updated and %d are ready", ds.Status.UpdatedNumberScheduled, desired, ds.Status.NumberReady)
 	}
 
-	if nginx.Spec.Ingress == nil {
-		return r.Client.Delete(ctx, &currentIngress)
+	degraded := metav1.Condition{
+		Type:               v1alpha1.NginxConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: nginx.Generation,
+		Reason:             "AsExpected",
 	}
 
-	if !shouldUpdateIngress(&currentIngress, newIngress) {
-		return nil
+	if ds.Status.NumberMisscheduled > 0 {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = "PodsMisscheduled"
+		degraded.Message = fmt.Sprintf("%d pods are running on nodes they shouldn't", ds.Status.NumberMisscheduled)
 	}
 
-	for key, value := range currentIngress.Annotations {
-		if newIngress.Annotations[key] == "" {
-			newIngress.Annotations[key] = value
-		}
+	if reason, message := podFailure(pods); reason != "" {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = reason
+		degraded.Message = message
 	}
 
-	newIngress.ResourceVersion = currentIngress.ResourceVersion
-	newIngress.Finalizers = currentIngress.Finalizers
+	meta.SetStatusCondition(&status.Conditions, available)
+	meta.SetStatusCondition(&status.Conditions, progressing)
+	meta.SetStatusCondition(&status.Conditions, degraded)
+}
+
+func (r *NginxReconciler) setReconcileFailedCondition(ctx context.Context, nginx *nginxv1alpha1.Nginx, reconcileErr error) error {
+	condition := metav1.Condition{
+		Type:               v1alpha1.NginxConditionDegraded,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: nginx.Generation,
+		Reason:             "ReconcileFailed",
+		Message:            reconcileErr.Error(),
+	}
+
+	if c := meta.FindStatusCondition(nginx.Status.Conditions, condition.Type); c != nil &&
+		c.Status == condition.Status && c.Reason == condition.Reason && c.Message == condition.Message && c.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
 
-	return r.Client.Update(ctx, newIngress)
+	patch := client.MergeFrom(nginx.DeepCopy())
+	meta.SetStatusCondition(&nginx.Status.Conditions, condition)
+
+	return r.Client.Status().Patch(ctx, nginx, patch)
 }
 
-func shouldUpdateIngress(currentIngress, newIngress *networkingv1.Ingress) bool {
-	if currentIngress == nil || newIngress == nil {
-		return false
+// deploymentRollingOut returns whether the Deployment has pods yet to be
+// updated or ready.
+func deploymentRollingOut(deploy *appsv1.Deployment) bool {
+	var desired int32 = 1
+	if deploy.Spec.Replicas != nil {
+		desired = *deploy.Spec.Replicas
 	}
 
-	for key, value := range newIngress.Annotations {
-		if currentIngress.Annotations[key] != value {
-			return true
+	return deploy.Status.ObservedGeneration < deploy.Generation ||
+		deploy.Status.UpdatedReplicas < desired ||
+		deploy.Status.ReadyReplicas < desired ||
+		deploy.Status.Replicas > deploy.Status.UpdatedReplicas
+}
+
+func findDeployment(deploys []appsv1.Deployment, name string) *appsv1.Deployment {
+	for i := range deploys {
+		if deploys[i].Name == name {
+			return &deploys[i]
 		}
 	}
 
-	return !reflect.DeepEqual(currentIngress.Labels, newIngress.Labels) ||
-		!reflect.DeepEqual(currentIngress.Spec, newIngress.Spec)
+	return nil
 }
 
-func (r *NginxReconciler) refreshStatus(ctx context.Context, nginx *nginxv1alpha1.Nginx) error {
-	deploys, err := listDeployments(ctx, r.Client, nginx)
-	if err != nil {
-		return err
+func deploymentCondition(deploy *appsv1.Deployment, t appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
+	for i := range deploy.Status.Conditions {
+		if deploy.Status.Conditions[i].Type == t {
+			return &deploy.Status.Conditions[i]
+		}
 	}
 
-	var deployStatuses []v1alpha1.DeploymentStatus
-	var replicas int32
-	for _, d := range deploys {
-		replicas += d.Status.Replicas
-		deployStatuses = append(deployStatuses, v1alpha1.DeploymentStatus{Name: d.Name})
+	return nil
+}
+
+func podFailure(pods []corev1.Pod) (string, string) {
+	for _, pod := range pods {
+		statuses := append(slices.Clone(pod.Status.InitContainerStatuses), pod.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			if oomKilled(cs) {
+				return "OOMKilled", fmt.Sprintf("pod %s, container %s: terminated due to out of memory", pod.Name, cs.Name)
+			}
+
+			if cs.State.Waiting == nil || !slices.Contains(podFailureReasons, cs.State.Waiting.Reason) {
+				continue
+			}
+
+			return cs.State.Waiting.Reason, fmt.Sprintf("pod %s, container %s: %s", pod.Name, cs.Name, cs.State.Waiting.Message)
+		}
 	}
 
-	services, err := listServices(ctx, r.Client, nginx)
-	if err != nil {
-		return fmt.Errorf("failed to list services for nginx: %v", err)
+	return "", ""
+}
+
+// oomKilled returns whether the container was killed for running out of
+// memory and hasn't come back yet, e.g. it's in a crash loop.
+func oomKilled(cs corev1.ContainerStatus) bool {
+	if t := cs.State.Terminated; t != nil {
+		return t.Reason == "OOMKilled"
 	}
 
-	ingresses, err := listIngresses(ctx, r.Client, nginx)
-	if err != nil {
-		return fmt.Errorf("failed to list ingresses for nginx: %w", err)
+	if t := cs.LastTerminationState.Terminated; t != nil && t.Reason == "OOMKilled" {
+		return cs.State.Waiting != nil && cs.State.Waiting.Reason == "CrashLoopBackOff"
 	}
 
-	sort.Slice(nginx.Status.Services, func(i, j int) bool {
-		return nginx.Status.Services[i].Name < nginx.Status.Services[j].Name
-	})
+	return false
+}
 
-	sort.Slice(nginx.Status.Ingresses, func(i, j int) bool {
-		return nginx.Status.Ingresses[i].Name < nginx.Status.Ingresses[j].Name
-	})
+func podStatuses(pods []corev1.Pod) []v1alpha1.PodStatus {
+	var statuses []v1alpha1.PodStatus
+	for _, pod := range pods {
+		status := v1alpha1.PodStatus{
+			Name:      pod.Name,
+			PodIP:     pod.Status.PodIP,
+			HostIP:    pod.Status.HostIP,
+			NodeName:  pod.Spec.NodeName,
+			StartTime: pod.Status.StartTime,
+		}
 
-	status := v1alpha1.NginxStatus{
-		CurrentReplicas: replicas,
-		PodSelector:     k8s.LabelsForNginxString(nginx.Name),
-		Deployments:     deployStatuses,
-		Services:        services,
-		Ingresses:       ingresses,
-	}
+		for _, c := range pod.Status.Conditions {
+			if c.Type == corev1.PodReady {
+				status.Ready = c.Status == corev1.ConditionTrue
+			}
+		}
 
-	if reflect.DeepEqual(nginx.Status, status) {
-		return nil
+		for _, c := range pod.Spec.Containers {
+			if c.Name == "nginx" {
+				status.Image = c.Image
+			}
+		}
+
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name == "nginx" && cs.ImageID != "" {
+				status.Image, status.ImageID = cs.Image, cs.ImageID
+			}
+		}
+
+		status.Version = nginxVersion(status.Image)
+		statuses = append(statuses, status)
 	}
 
-	nginx.Status = status
+	return statuses
+}
+
+func listPods(ctx context.Context, c client.Client, nginx *nginxv1alpha1.Nginx) ([]corev1.Pod, error) {
+	var podList corev1.PodList
 
-	err = r.Client.Status().Update(ctx, nginx)
+	err := c.List(ctx, &podList, &client.ListOptions{
+		Namespace:     nginx.Namespace,
+		LabelSelector: labels.SelectorFromSet(k8s.LabelsForNginx(nginx.Name)),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update nginx status: %v", err)
+		return nil, err
 	}
 
-	return nil
+	sort.Slice(podList.Items, func(i, j int) bool {
+		return podList.Items[i].Name < podList.Items[j].Name
+	})
+
+	return podList.Items, nil
 }
 
 func listDeployments(ctx context.Context, c client.Client, nginx *nginxv1alpha1.Nginx) ([]appsv1.Deployment, error) {
@@ -389,6 +2373,22 @@ func listServices(ctx context.Context, c client.Client, nginx *nginxv1alpha1.Ngi
 			Name: s.Name,
 		}
 
+		if s.Spec.ClusterIP != corev1.ClusterIPNone {
+			svc.ClusterIP = s.Spec.ClusterIP
+		}
+
+		for _, port := range s.Spec.Ports {
+			if port.NodePort == 0 {
+				continue
+			}
+
+			svc.NodePorts = append(svc.NodePorts, nginxv1alpha1.ServiceNodePort{
+				Name:     port.Name,
+				Port:     port.Port,
+				NodePort: port.NodePort,
+			})
+		}
+
 		for _, ingStatus := range s.Status.LoadBalancer.Ingress {
 			if ingStatus.IP != "" {
 				svc.IPs = append(svc.IPs, ingStatus.IP)
@@ -8,29 +8,40 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/tsuru/nginx-operator/api/v1alpha1"
+	"github.com/tsuru/nginx-operator/pkg/k8s"
 )
 
 func TestNginxReconciler_reconcileDeployment(t *testing.T) {
@@ -57,6 +68,28 @@ func TestNginxReconciler_reconcileDeployment(t *testing.T) {
 				},
 			},
 		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "nginx-2",
+				Namespace: "default",
+				Annotations: map[string]string{
+					"nginx.tsuru.io/generated-from": `{"replicas": 3, "image": "nginx:stable", "podTemplate": {"ports": [{"name": "http", "containerPort": 8080, "protocol": "TCP"}, {"name": "https", "containerPort": 8443, "protocol": "TCP"}]}}`,
+				},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: func(n int32) *int32 { return &n }(int32(1)),
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "nginx",
+								Image: "nginx:1.21",
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	tests := map[string]struct {
@@ -138,6 +171,28 @@ func TestNginxReconciler_reconcileDeployment(t *testing.T) {
 				assert.Equal(t, expected, nginxSpec)
 			},
 		},
+
+		"when deployment was manually changed, should revert it to the Nginx spec": {
+			nginx: &v1alpha1.Nginx{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "nginx-2",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.NginxSpec{
+					Replicas: func(n int32) *int32 { return &n }(int32(3)),
+					Image:    "nginx:stable",
+				},
+			},
+			assert: func(t *testing.T, c client.Client) {
+				var dep appsv1.Deployment
+				err := c.Get(context.TODO(), types.NamespacedName{Name: "nginx-2", Namespace: "default"}, &dep)
+				require.NoError(t, err)
+
+				require.NotNil(t, dep.Spec.Replicas)
+				assert.Equal(t, int32(3), *dep.Spec.Replicas)
+				assert.Equal(t, "nginx:stable", dep.Spec.Template.Spec.Containers[0].Image)
+			},
+		},
 	}
 
 	for name, tt := range tests {
@@ -150,8 +205,9 @@ func TestNginxReconciler_reconcileDeployment(t *testing.T) {
 				Build()
 
 			r := &NginxReconciler{
-				Client: client,
-				Log:    ctrl.Log.WithName("test"),
+				Client:        client,
+				EventRecorder: record.NewFakeRecorder(100),
+				Log:           ctrl.Log.WithName("test"),
 			}
 
 			err := r.reconcileDeployment(context.TODO(), tt.nginx)
@@ -162,222 +218,718 @@ func TestNginxReconciler_reconcileDeployment(t *testing.T) {
 	}
 }
 
-func TestNginxReconciler_reconcileService(t *testing.T) {
-	tests := []struct {
-		name           string
-		nginx          *v1alpha1.Nginx
-		service        *corev1.Service
-		assertion      func(t *testing.T, err error, got *corev1.Service)
-		expectedEvents []string
-	}{
-		{
-			name: "when service doesn't exist yet, should create that and use an auto-allocated nodeport (tcp/0)",
-			nginx: &v1alpha1.Nginx{
-				TypeMeta: metav1.TypeMeta{
-					APIVersion: "extensions.tsuru.io/v1alpha1",
-					Kind:       "Nginx",
-				},
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "my-nginx",
-					Namespace: "default",
+func TestNginxReconciler_reconcileDeployment_adoption(t *testing.T) {
+	handMade := func() *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default", Labels: map[string]string{"team": "web"}},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To(int32(2)),
+				Selector: &metav1.LabelSelector{MatchLabels: k8s.LabelsForNginx("my-nginx")},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: k8s.LabelsForNginx("my-nginx")},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx:1.21"}}},
 				},
-				Spec:   v1alpha1.NginxSpec{},
-				Status: v1alpha1.NginxStatus{},
-			},
-			assertion: func(t *testing.T, err error, got *corev1.Service) {
-				assert.NoError(t, err)
-				assert.NotNil(t, got)
-				expectedPorts := []corev1.ServicePort{
-					{
-						Name:       "http",
-						TargetPort: intstr.FromString("http"),
-						Protocol:   corev1.ProtocolTCP,
-						NodePort:   int32(0),
-						Port:       int32(80),
-					},
-					{
-						Name:       "https",
-						TargetPort: intstr.FromString("https"),
-						Protocol:   corev1.ProtocolTCP,
-						NodePort:   int32(0),
-						Port:       int32(443),
-					},
-				}
-				assert.Equal(t, expectedPorts, got.Spec.Ports)
 			},
-			expectedEvents: []string{
-				"Normal ServiceCreated service created successfully",
+		}
+	}
+
+	tests := map[string]struct {
+		annotations   map[string]string
+		deploy        func() *appsv1.Deployment
+		expectedError string
+		assert        func(t *testing.T, dep *appsv1.Deployment, events []string)
+	}{
+		"without the adopt annotation, should fail": {
+			deploy:        handMade,
+			expectedError: `Deployment "my-nginx" already exists and is not managed by this Nginx`,
+		},
+
+		"with the adopt annotation, should take over the Deployment": {
+			annotations: map[string]string{"nginx.tsuru.io/adopt": "true"},
+			deploy:      handMade,
+			assert: func(t *testing.T, dep *appsv1.Deployment, events []string) {
+				require.Len(t, dep.OwnerReferences, 1)
+				assert.Equal(t, "Nginx", dep.OwnerReferences[0].Kind)
+				assert.Equal(t, "my-nginx", dep.OwnerReferences[0].Name)
+				assert.Equal(t, "web", dep.Labels["team"])
+				assert.Equal(t, "nginx:stable", dep.Spec.Template.Spec.Containers[0].Image)
+				assert.NotEmpty(t, dep.Annotations["nginx.tsuru.io/generated-from"])
+				assert.Equal(t, []string{"Normal DeploymentAdopted deployment adopted successfully"}, events)
 			},
 		},
-		{
-			name: "when setup an https port to the service, should use an auto-allocated nodeport on https and preserve its http nodeport",
-			nginx: &v1alpha1.Nginx{
-				TypeMeta: metav1.TypeMeta{
-					APIVersion: "extensions.tsuru.io/v1alpha1",
-					Kind:       "Nginx",
-				},
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "my-nginx",
-					Namespace: "default",
-				},
-				Spec: v1alpha1.NginxSpec{
-					TLS: []v1alpha1.NginxTLS{{SecretName: "my-secret"}},
-					Service: &v1alpha1.NginxService{
-						Type: corev1.ServiceTypeNodePort,
-					},
-				},
-				Status: v1alpha1.NginxStatus{},
+
+		"when the pod selector differs, should fail": {
+			annotations: map[string]string{"nginx.tsuru.io/adopt": "true"},
+			deploy: func() *appsv1.Deployment {
+				d := handMade()
+				d.Spec.Selector.MatchLabels = map[string]string{"app": "nginx"}
+				return d
+			},
+			expectedError: "its pod selector differs from the Nginx one",
+		},
+
+		"when controlled by another resource, should fail": {
+			annotations: map[string]string{"nginx.tsuru.io/adopt": "true"},
+			deploy: func() *appsv1.Deployment {
+				d := handMade()
+				d.OwnerReferences = []metav1.OwnerReference{{APIVersion: "example.com/v1", Kind: "App", Name: "web", UID: "1234", Controller: ptr.To(true)}}
+				return d
+			},
+			expectedError: `cannot adopt Deployment "my-nginx": it's controlled by App "web"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			nginx := &v1alpha1.Nginx{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default", Annotations: tt.annotations},
+				Spec:       v1alpha1.NginxSpec{Image: "nginx:stable"},
+			}
+
+			client := fake.NewClientBuilder().
+				WithScheme(newScheme()).
+				WithRuntimeObjects(tt.deploy()).
+				Build()
+
+			recorder := record.NewFakeRecorder(100)
+			r := &NginxReconciler{Client: client, EventRecorder: recorder}
+
+			err := r.reconcileDeployment(context.TODO(), nginx)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			var dep appsv1.Deployment
+			require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep))
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+
+			tt.assert(t, &dep, events)
+		})
+	}
+}
+
+func TestNginxReconciler_driftPolicyTolerate(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			Image:       "nginx:stable",
+			DriftPolicy: v1alpha1.DriftPolicyTolerate,
+			Service:     &v1alpha1.NginxService{Type: corev1.ServiceTypeClusterIP},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+
+	reconcileAll := func() {
+		require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+		require.NoError(t, r.reconcileService(context.TODO(), nginx.DeepCopy()))
+	}
+
+	reconcileAll()
+
+	var dep appsv1.Deployment
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep))
+	dep.Spec.Template.Spec.Containers[0].Image = "nginx:hotfix"
+	require.NoError(t, client.Update(context.TODO(), &dep))
+
+	var svc corev1.Service
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-service", Namespace: "default"}, &svc))
+	assert.NotEmpty(t, svc.Annotations["nginx.tsuru.io/service-checksum"])
+	svc.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/8"}
+	require.NoError(t, client.Update(context.TODO(), &svc))
+
+	reconcileAll()
+
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep))
+	assert.Equal(t, "nginx:hotfix", dep.Spec.Template.Spec.Containers[0].Image)
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-service", Namespace: "default"}, &svc))
+	assert.Equal(t, []string{"10.0.0.0/8"}, svc.Spec.LoadBalancerSourceRanges)
+
+	nginx.Spec.Image = "nginx:1.25"
+	nginx.Spec.Service.Labels = map[string]string{"team": "web"}
+	reconcileAll()
+
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep))
+	assert.Equal(t, "nginx:1.25", dep.Spec.Template.Spec.Containers[0].Image)
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-service", Namespace: "default"}, &svc))
+	assert.Equal(t, "web", svc.Labels["team"])
+	assert.Empty(t, svc.Spec.LoadBalancerSourceRanges)
+}
+
+func TestNginxReconciler_reconcileDeployment_drift(t *testing.T) {
+	tests := map[string]struct {
+		edit    func(dep *appsv1.Deployment)
+		updated bool
+	}{
+		"when the resources are changed by hand, should revert them": {
+			edit: func(dep *appsv1.Deployment) {
+				dep.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory] = resource.MustParse("1Gi")
 			},
-			service: &corev1.Service{
-				TypeMeta: metav1.TypeMeta{
-					APIVersion: "v1",
-					Kind:       "Service",
-				},
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "my-nginx-service",
-					Namespace: "default",
-				},
-				Spec: corev1.ServiceSpec{
-					Type: corev1.ServiceTypeNodePort,
-					Ports: []corev1.ServicePort{
-						{
-							Name:       "https",
-							TargetPort: intstr.FromString("https"),
-							Protocol:   corev1.ProtocolTCP,
-							Port:       int32(443),
-							NodePort:   int32(30667),
-						},
-						{
-							Name:       "http",
-							Protocol:   corev1.ProtocolTCP,
-							TargetPort: intstr.FromString("http"),
-							Port:       int32(80),
-							NodePort:   int32(30666),
-						},
-					},
-				},
+			updated: true,
+		},
+
+		"when the env is changed by hand, should revert it": {
+			edit: func(dep *appsv1.Deployment) {
+				dep.Spec.Template.Spec.Containers[0].Env[0].Value = "debug"
 			},
-			assertion: func(t *testing.T, err error, got *corev1.Service) {
-				assert.NoError(t, err)
-				assert.NotNil(t, got)
-				expectedPorts := []corev1.ServicePort{
-					{
-						Name:       "http",
-						TargetPort: intstr.FromString("http"),
-						Protocol:   corev1.ProtocolTCP,
-						NodePort:   int32(30666),
-						Port:       int32(80),
-					},
-					{
-						Name:       "https",
-						TargetPort: intstr.FromString("https"),
-						Protocol:   corev1.ProtocolTCP,
-						NodePort:   int32(30667),
-						Port:       int32(443),
-					},
-				}
-				assert.Equal(t, expectedPorts, got.Spec.Ports)
+			updated: true,
+		},
+
+		"when the strategy is changed by hand, should revert it": {
+			edit: func(dep *appsv1.Deployment) {
+				dep.Spec.Strategy.Type = appsv1.RecreateDeploymentStrategyType
+				dep.Spec.Strategy.RollingUpdate = nil
 			},
-			expectedEvents: []string{
-				"Normal ServiceUpdated service updated successfully",
+			updated: true,
+		},
+
+		"when only fields defaulted by the API server are set, should not update it": {
+			edit: func(dep *appsv1.Deployment) {
+				c := &dep.Spec.Template.Spec.Containers[0]
+				c.TerminationMessagePath = corev1.TerminationMessagePathDefault
+				c.ReadinessProbe.PeriodSeconds = 10
+				c.ReadinessProbe.SuccessThreshold = 1
+				c.ReadinessProbe.FailureThreshold = 3
+				dep.Spec.Template.Spec.SchedulerName = corev1.DefaultSchedulerName
+				dep.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"] = "2026-01-01T00:00:00Z"
 			},
 		},
-		{
-			name: "when updating the nginx service field, should update the service resource as well",
-			nginx: &v1alpha1.Nginx{
-				TypeMeta: metav1.TypeMeta{
-					APIVersion: "extensions.tsuru.io/v1alpha1",
-					Kind:       "Nginx",
-				},
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "my-nginx",
-					Namespace: "default",
-				},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			nginx := &v1alpha1.Nginx{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
 				Spec: v1alpha1.NginxSpec{
-					Service: &v1alpha1.NginxService{
-						Type: corev1.ServiceTypeLoadBalancer,
-						Annotations: map[string]string{
-							"nginx.tsuru.io/new-annotation": "v1",
-						},
-						Labels: map[string]string{
-							"nginx.tsuru.io/new-label": "v1",
-						},
-					},
-					TLS: []v1alpha1.NginxTLS{{SecretName: "my-secret"}},
-				},
-			},
-			service: &corev1.Service{
-				TypeMeta: metav1.TypeMeta{
-					APIVersion: "v1",
-					Kind:       "Service",
-				},
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "my-nginx-service",
-					Namespace: "default",
-					Annotations: map[string]string{
-						"old-service-annotation": "v1",
+					Image: "nginx:stable",
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
 					},
-					Labels: map[string]string{
-						"old-service-label": "v1",
+					PodTemplate: v1alpha1.NginxPodTemplateSpec{
+						Env: []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}},
 					},
 				},
-				Spec: corev1.ServiceSpec{
-					ClusterIP:           "10.1.1.10",
-					HealthCheckNodePort: int32(43123),
-					Ports: []corev1.ServicePort{
-						{
-							Name:       "https",
-							TargetPort: intstr.FromString("https"),
-							Protocol:   corev1.ProtocolTCP,
-							Port:       int32(443),
-							NodePort:   int32(30667),
-						},
-						{
-							Name:       "http",
-							Protocol:   corev1.ProtocolTCP,
-							TargetPort: intstr.FromString("http"),
-							Port:       int32(80),
-							NodePort:   int32(30666),
-						},
+			}
+
+			client := fake.NewClientBuilder().
+				WithScheme(newScheme()).
+				Build()
+
+			recorder := record.NewFakeRecorder(100)
+			r := &NginxReconciler{Client: client, EventRecorder: recorder}
+			require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+
+			var dep appsv1.Deployment
+			require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep))
+			expected := dep.Spec.DeepCopy()
+
+			if dep.Spec.Template.Annotations == nil {
+				dep.Spec.Template.Annotations = map[string]string{}
+			}
+			tt.edit(&dep)
+			require.NoError(t, client.Update(context.TODO(), &dep))
+			edited := dep.Spec.DeepCopy()
+
+			require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+
+			require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep))
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+
+			if tt.updated {
+				assert.Equal(t, *expected, dep.Spec)
+				assert.Contains(t, events, "Normal DeploymentUpdated deployment updated successfully")
+			} else {
+				assert.Equal(t, *edited, dep.Spec)
+				assert.NotContains(t, events, "Normal DeploymentUpdated deployment updated successfully")
+			}
+		})
+	}
+}
+
+func TestNginxReconciler_reconcileDeployment_surgeOnUpgrade(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			Image:    "nginx:1.24.0",
+			Replicas: ptr.To(int32(2)),
+			Strategy: &v1alpha1.NginxStrategy{SurgeOnUpgrade: true},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(nginx.DeepCopy()).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+
+	getDeployment := func() *appsv1.Deployment {
+		var dep appsv1.Deployment
+		require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep))
+		return &dep
+	}
+
+	defaultStrategy := func() appsv1.DeploymentStrategy {
+		dep, err := k8s.NewDeployment(nginx)
+		require.NoError(t, err)
+		return dep.Spec.Strategy
+	}
+
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	assert.Equal(t, defaultStrategy(), getDeployment().Spec.Strategy)
+
+	nginx.Spec.Image = "nginx:1.25.3-alpine"
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+
+	dep := getDeployment()
+	assert.Equal(t, "100%", dep.Spec.Strategy.RollingUpdate.MaxSurge.String())
+	assert.Equal(t, "0", dep.Spec.Strategy.RollingUpdate.MaxUnavailable.String())
+	assert.Equal(t, "1.24.0", dep.Annotations["nginx.tsuru.io/upgrade-from-version"])
+	assert.Equal(t, "1.25.3", dep.Annotations["nginx.tsuru.io/upgrade-to-version"])
+
+	var got v1alpha1.Nginx
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got))
+	require.NoError(t, r.refreshStatus(context.TODO(), &got))
+	assert.Equal(t, &v1alpha1.NginxUpgradeStatus{FromVersion: "1.24.0", ToVersion: "1.25.3"}, got.Status.Upgrade)
+
+	// NOTE: keeps surging while the rollout is in progress.
+	dep.Status = appsv1.DeploymentStatus{Replicas: 4, UpdatedReplicas: 2, ReadyReplicas: 4}
+	require.NoError(t, client.Status().Update(context.TODO(), dep))
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	assert.Equal(t, "100%", getDeployment().Spec.Strategy.RollingUpdate.MaxSurge.String())
+
+	dep = getDeployment()
+	dep.Status = appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2}
+	require.NoError(t, client.Status().Update(context.TODO(), dep))
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+
+	dep = getDeployment()
+	assert.Equal(t, defaultStrategy(), dep.Spec.Strategy)
+	assert.NotContains(t, dep.Annotations, "nginx.tsuru.io/upgrade-from-version")
+	assert.NotContains(t, dep.Annotations, "nginx.tsuru.io/upgrade-to-version")
+
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got))
+	require.NoError(t, r.refreshStatus(context.TODO(), &got))
+	assert.Nil(t, got.Status.Upgrade)
+
+	nginx.Spec.Image = "nginx:1.25.4-alpine"
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	assert.Equal(t, defaultStrategy(), getDeployment().Spec.Strategy)
+}
+
+func TestNginxVersion(t *testing.T) {
+	tests := map[string]string{
+		"nginx:1.25.3":                 "1.25.3",
+		"nginx:1.25.3-alpine":          "1.25.3",
+		"nginx:1.25":                   "1.25",
+		"nginx:v1.25.3":                "1.25.3",
+		"registry:5000/nginx:1.24.0":   "1.24.0",
+		"nginx:1.24.0@sha256:deadbeef": "1.24.0",
+		"registry:5000/nginx":          "",
+		"nginx:stable":                 "",
+		"nginx":                        "",
+		"nginx@sha256:deadbeef":        "",
+	}
+
+	for image, expected := range tests {
+		assert.Equal(t, expected, nginxVersion(image), image)
+	}
+}
+
+func TestNginxReconciler_reconcileWorkload(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			Image:          "nginx:stable",
+			DeploymentMode: v1alpha1.DeploymentModeDaemonSet,
+		},
+	}
+
+	deploy, err := k8s.NewDeployment(nginx)
+	require.NoError(t, err)
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(deploy).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+	require.NoError(t, r.reconcileWorkload(context.TODO(), nginx))
+
+	var ds appsv1.DaemonSet
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &ds))
+	assert.Equal(t, k8s.LabelsForNginx("my-nginx"), ds.Spec.Selector.MatchLabels)
+	assert.Equal(t, "nginx:stable", ds.Spec.Template.Spec.Containers[0].Image)
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &appsv1.Deployment{})
+	assert.True(t, errors.IsNotFound(err))
+
+	nginx.Spec.Image = "nginx:1.25"
+	require.NoError(t, r.reconcileWorkload(context.TODO(), nginx))
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &ds))
+	assert.Equal(t, "nginx:1.25", ds.Spec.Template.Spec.Containers[0].Image)
+
+	nginx.Spec.DeploymentMode = v1alpha1.DeploymentModeDeployment
+	require.NoError(t, r.reconcileWorkload(context.TODO(), nginx))
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &appsv1.Deployment{}))
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &appsv1.DaemonSet{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestNginxReconciler_reconcileDeployment_configChecksum(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			Config: &v1alpha1.ConfigRef{Kind: v1alpha1.ConfigKindConfigMap, Name: "my-config"},
+		},
+	}
+
+	config := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-config", Namespace: "default"},
+		Data:       map[string]string{"nginx.conf": "events {}"},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(config).
+		Build()
+
+	r := &NginxReconciler{
+		Client:        client,
+		EventRecorder: record.NewFakeRecorder(100),
+		Log:           ctrl.Log.WithName("test"),
+	}
+
+	getChecksum := func() string {
+		var dep appsv1.Deployment
+		err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep)
+		require.NoError(t, err)
+		return dep.Spec.Template.Annotations["nginx.tsuru.io/config-checksum"]
+	}
+
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	firstChecksum := getChecksum()
+	assert.NotEmpty(t, firstChecksum)
+
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	assert.Equal(t, firstChecksum, getChecksum())
+
+	config.Data["nginx.conf"] = "events {} http {}"
+	require.NoError(t, client.Update(context.TODO(), config))
+
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	secondChecksum := getChecksum()
+	assert.NotEmpty(t, secondChecksum)
+	assert.NotEqual(t, firstChecksum, secondChecksum)
+}
+
+func TestNginxReconciler_reconcileDeployment_checksumsFromAPIReader(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			Config: &v1alpha1.ConfigRef{Kind: v1alpha1.ConfigKindConfigMap, Name: "my-config"},
+			TLS:    []v1alpha1.NginxTLS{{SecretName: "my-cert"}},
+		},
+	}
+
+	// NOTE: the ConfigMaps and Secrets are only known by the API reader, as
+	// the cache holds just their metadata.
+	apiReader := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-config", Namespace: "default"},
+				Data:       map[string]string{"nginx.conf": "events {}"},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-cert", Namespace: "default"},
+				Data:       map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key")},
+			},
+		).
+		Build()
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		Build()
+
+	r := &NginxReconciler{
+		Client:        client,
+		APIReader:     apiReader,
+		EventRecorder: record.NewFakeRecorder(100),
+		Log:           ctrl.Log.WithName("test"),
+	}
+
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+
+	var dep appsv1.Deployment
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep))
+
+	expected, err := (&NginxReconciler{Client: apiReader}).configChecksum(context.TODO(), nginx)
+	require.NoError(t, err)
+	missing, err := (&NginxReconciler{Client: client}).configChecksum(context.TODO(), nginx)
+	require.NoError(t, err)
+	require.NotEqual(t, missing, expected)
+	assert.Equal(t, expected, dep.Spec.Template.Annotations["nginx.tsuru.io/config-checksum"])
+
+	expected, err = (&NginxReconciler{Client: apiReader}).tlsChecksum(context.TODO(), nginx)
+	require.NoError(t, err)
+	assert.Equal(t, expected, dep.Spec.Template.Annotations["nginx.tsuru.io/tls-checksum"])
+}
+
+func TestNginxReconciler_reconcileDeployment_configFilesChecksum(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			Config: &v1alpha1.ConfigRef{
+				Kind: v1alpha1.ConfigKindConfigMap,
+				Name: "my-config",
+				ExtraFiles: &v1alpha1.NginxConfigFiles{
+					Sources: []v1alpha1.NginxConfigFileSource{
+						{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "my-upstreams"}}},
+						{Secret: &corev1.SecretProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "my-htpasswd"}}},
 					},
 				},
 			},
+		},
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-htpasswd", Namespace: "default"},
+		Data:       map[string][]byte{"htpasswd": []byte("admin:xxx")},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(secret, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-upstreams", Namespace: "default"},
+			Data:       map[string]string{"upstreams.conf": "upstream app {}"},
+		}).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+
+	getChecksum := func() string {
+		var dep appsv1.Deployment
+		err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep)
+		require.NoError(t, err)
+		return dep.Spec.Template.Annotations["nginx.tsuru.io/config-checksum"]
+	}
+
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	firstChecksum := getChecksum()
+	assert.NotEmpty(t, firstChecksum)
+
+	secret.Data["htpasswd"] = []byte("admin:yyy")
+	require.NoError(t, client.Update(context.TODO(), secret))
+
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	assert.NotEqual(t, firstChecksum, getChecksum())
+
+	assert.True(t, usesConfigMap(nginx, "my-upstreams"))
+	assert.True(t, usesSecret(nginx, "my-htpasswd"))
+}
+
+func TestNginxReconciler_reconcileDeployment_validateConfig(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			ValidateConfig: true,
+			Config:         &v1alpha1.ConfigRef{Kind: v1alpha1.ConfigKindInline, Value: "events {}"},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		Build()
+
+	r := &NginxReconciler{
+		Client:        client,
+		EventRecorder: record.NewFakeRecorder(100),
+		Log:           ctrl.Log.WithName("test"),
+	}
+
+	getConfig := func() string {
+		var dep appsv1.Deployment
+		err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep)
+		require.NoError(t, err)
+		return dep.Spec.Template.Annotations["nginx.tsuru.io/custom-nginx-config"]
+	}
+
+	getJobs := func() []batchv1.Job {
+		var jobs batchv1.JobList
+		require.NoError(t, client.List(context.TODO(), &jobs))
+		return jobs.Items
+	}
+
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	assert.Equal(t, "events {}", getConfig())
+	assert.Empty(t, getJobs())
+
+	nginx.Spec.Config.Value = "invalid"
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	assert.Equal(t, "events {}", getConfig())
+
+	jobs := getJobs()
+	require.Len(t, jobs, 1)
+	job := jobs[0]
+	assert.True(t, strings.HasPrefix(job.Name, "my-nginx-config-check-"))
+	assert.Equal(t, []string{"nginx", "-t"}, job.Spec.Template.Spec.Containers[0].Command)
+	assert.Equal(t, "invalid", job.Spec.Template.Annotations["nginx.tsuru.io/custom-nginx-config"])
+
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	assert.Equal(t, "events {}", getConfig())
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	require.NoError(t, client.Status().Update(context.TODO(), &job))
+
+	err := r.reconcileDeployment(context.TODO(), nginx.DeepCopy())
+	assert.EqualError(t, err, fmt.Sprintf("nginx configuration is invalid, see the logs of Job %s", job.Name))
+	assert.Equal(t, "events {}", getConfig())
+
+	nginx.Spec.Config.Value = "events {} http {}"
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	assert.Equal(t, "events {}", getConfig())
+
+	jobs = getJobs()
+	require.Len(t, jobs, 2)
+	for _, j := range jobs {
+		if j.Name == job.Name {
+			continue
+		}
+
+		j.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		require.NoError(t, client.Status().Update(context.TODO(), &j))
+	}
+
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	assert.Equal(t, "events {} http {}", getConfig())
+	assert.Empty(t, getJobs())
+}
+
+func TestConfigValidationJobName(t *testing.T) {
+	fingerprint := configFingerprint(&appsv1.Deployment{})
+
+	assert.Equal(t, "my-nginx-config-check-"+fingerprint[:10], configValidationJobName("my-nginx", fingerprint))
+
+	name := configValidationJobName(strings.Repeat("a", 38)+".b-"+strings.Repeat("c", 100), fingerprint)
+	assert.Equal(t, strings.Repeat("a", 38)+"-config-check-"+fingerprint[:10], name)
+	assert.Empty(t, validation.IsDNS1123Label(name))
+	assert.Empty(t, validation.IsValidLabelValue(name))
+}
+
+func TestNginxReconciler_reconcileDeployment_tlsChecksum(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			TLS: []v1alpha1.NginxTLS{{SecretName: "my-cert"}},
+		},
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-cert", Namespace: "default"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			"tls.crt": []byte("certificate"),
+			"tls.key": []byte("key"),
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(secret).
+		Build()
+
+	r := &NginxReconciler{
+		Client:        client,
+		EventRecorder: record.NewFakeRecorder(100),
+		Log:           ctrl.Log.WithName("test"),
+	}
+
+	getChecksum := func() string {
+		var dep appsv1.Deployment
+		err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep)
+		require.NoError(t, err)
+		return dep.Spec.Template.Annotations["nginx.tsuru.io/tls-checksum"]
+	}
+
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	firstChecksum := getChecksum()
+	assert.NotEmpty(t, firstChecksum)
+
+	secret.Data["tls.crt"] = []byte("renewed certificate")
+	require.NoError(t, client.Update(context.TODO(), secret))
+
+	require.NoError(t, r.reconcileDeployment(context.TODO(), nginx.DeepCopy()))
+	secondChecksum := getChecksum()
+	assert.NotEmpty(t, secondChecksum)
+	assert.NotEqual(t, firstChecksum, secondChecksum)
+}
+
+func TestNginxReconciler_reconcileService(t *testing.T) {
+	tests := []struct {
+		name           string
+		nginx          *v1alpha1.Nginx
+		service        *corev1.Service
+		assertion      func(t *testing.T, err error, got *corev1.Service)
+		expectedEvents []string
+	}{
+		{
+			name: "when service doesn't exist yet, should create that and use an auto-allocated nodeport (tcp/0)",
+			nginx: &v1alpha1.Nginx{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "extensions.tsuru.io/v1alpha1",
+					Kind:       "Nginx",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx",
+					Namespace: "default",
+				},
+				Spec:   v1alpha1.NginxSpec{},
+				Status: v1alpha1.NginxStatus{},
+			},
 			assertion: func(t *testing.T, err error, got *corev1.Service) {
 				assert.NoError(t, err)
 				assert.NotNil(t, got)
-				assert.Equal(t, got.Spec.ClusterIP, "10.1.1.10")
-				assert.Equal(t, got.Spec.Type, corev1.ServiceTypeLoadBalancer)
-				assert.Equal(t, got.Spec.HealthCheckNodePort, int32(43123))
 				expectedPorts := []corev1.ServicePort{
 					{
 						Name:       "http",
 						TargetPort: intstr.FromString("http"),
 						Protocol:   corev1.ProtocolTCP,
-						NodePort:   int32(30666),
+						NodePort:   int32(0),
 						Port:       int32(80),
 					},
 					{
 						Name:       "https",
 						TargetPort: intstr.FromString("https"),
 						Protocol:   corev1.ProtocolTCP,
-						NodePort:   int32(30667),
+						NodePort:   int32(0),
 						Port:       int32(443),
 					},
 				}
 				assert.Equal(t, expectedPorts, got.Spec.Ports)
-				assert.Equal(t, map[string]string{
-					"nginx.tsuru.io/app":           "nginx",
-					"nginx.tsuru.io/resource-name": "my-nginx",
-					"nginx.tsuru.io/new-label":     "v1",
-				}, got.Labels)
-				assert.Equal(t, map[string]string{"nginx.tsuru.io/new-annotation": "v1", "old-service-annotation": "v1"}, got.Annotations)
 			},
 			expectedEvents: []string{
-				"Normal ServiceUpdated service updated successfully",
+				"Normal ServiceCreated service created successfully",
 			},
 		},
 		{
-			name: "when updating the nginx service type, should discard nodeports when new service is clusterIP",
+			name: "when setup an https port to the service, should use an auto-allocated nodeport on https and preserve its http nodeport",
 			nginx: &v1alpha1.Nginx{
 				TypeMeta: metav1.TypeMeta{
 					APIVersion: "extensions.tsuru.io/v1alpha1",
@@ -388,11 +940,12 @@ func TestNginxReconciler_reconcileService(t *testing.T) {
 					Namespace: "default",
 				},
 				Spec: v1alpha1.NginxSpec{
+					TLS: []v1alpha1.NginxTLS{{SecretName: "my-secret"}},
 					Service: &v1alpha1.NginxService{
-						Type: corev1.ServiceTypeClusterIP,
+						Type: corev1.ServiceTypeNodePort,
 					},
-					TLS: []v1alpha1.NginxTLS{{SecretName: "my-secret"}},
 				},
+				Status: v1alpha1.NginxStatus{},
 			},
 			service: &corev1.Service{
 				TypeMeta: metav1.TypeMeta{
@@ -400,16 +953,11 @@ func TestNginxReconciler_reconcileService(t *testing.T) {
 					Kind:       "Service",
 				},
 				ObjectMeta: metav1.ObjectMeta{
-					Name:        "my-nginx-service",
-					Namespace:   "default",
-					Annotations: map[string]string{},
-					Labels:      map[string]string{},
+					Name:      "my-nginx-service",
+					Namespace: "default",
 				},
 				Spec: corev1.ServiceSpec{
-					Type:                  corev1.ServiceTypeLoadBalancer,
-					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeCluster,
-					ClusterIP:             "10.1.1.10",
-					HealthCheckNodePort:   int32(43123),
+					Type: corev1.ServiceTypeNodePort,
 					Ports: []corev1.ServicePort{
 						{
 							Name:       "https",
@@ -431,21 +979,19 @@ func TestNginxReconciler_reconcileService(t *testing.T) {
 			assertion: func(t *testing.T, err error, got *corev1.Service) {
 				assert.NoError(t, err)
 				assert.NotNil(t, got)
-				assert.Equal(t, got.Spec.ClusterIP, "10.1.1.10")
-				assert.Equal(t, got.Spec.Type, corev1.ServiceTypeClusterIP)
-				assert.Equal(t, got.Spec.ExternalTrafficPolicy, corev1.ServiceExternalTrafficPolicyType(""))
-				assert.Equal(t, got.Spec.HealthCheckNodePort, int32(43123))
 				expectedPorts := []corev1.ServicePort{
 					{
 						Name:       "http",
 						TargetPort: intstr.FromString("http"),
 						Protocol:   corev1.ProtocolTCP,
+						NodePort:   int32(30666),
 						Port:       int32(80),
 					},
 					{
 						Name:       "https",
 						TargetPort: intstr.FromString("https"),
 						Protocol:   corev1.ProtocolTCP,
+						NodePort:   int32(30667),
 						Port:       int32(443),
 					},
 				}
@@ -456,7 +1002,7 @@ func TestNginxReconciler_reconcileService(t *testing.T) {
 			},
 		},
 		{
-			name: "when updating the nginx service, should maintain other controller's annotations",
+			name: "when updating the nginx service field, should update the service resource as well",
 			nginx: &v1alpha1.Nginx{
 				TypeMeta: metav1.TypeMeta{
 					APIVersion: "extensions.tsuru.io/v1alpha1",
@@ -468,11 +1014,15 @@ func TestNginxReconciler_reconcileService(t *testing.T) {
 				},
 				Spec: v1alpha1.NginxSpec{
 					Service: &v1alpha1.NginxService{
-						Type: corev1.ServiceTypeClusterIP,
+						Type: corev1.ServiceTypeLoadBalancer,
 						Annotations: map[string]string{
-							"annotation-from-this-controller": "updated",
+							"nginx.tsuru.io/new-annotation": "v1",
+						},
+						Labels: map[string]string{
+							"nginx.tsuru.io/new-label": "v1",
 						},
 					},
+					TLS: []v1alpha1.NginxTLS{{SecretName: "my-secret"}},
 				},
 			},
 			service: &corev1.Service{
@@ -484,13 +1034,182 @@ func TestNginxReconciler_reconcileService(t *testing.T) {
 					Name:      "my-nginx-service",
 					Namespace: "default",
 					Annotations: map[string]string{
-						"annotation-from-this-controller":    "please-update",
-						"annotation-from-another-controller": "please-keep-it",
+						"old-service-annotation": "v1",
+					},
+					Labels: map[string]string{
+						"old-service-label": "v1",
 					},
-					Labels: map[string]string{},
 				},
 				Spec: corev1.ServiceSpec{
-					Type:                  corev1.ServiceTypeLoadBalancer,
+					ClusterIP:           "10.1.1.10",
+					HealthCheckNodePort: int32(43123),
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "https",
+							TargetPort: intstr.FromString("https"),
+							Protocol:   corev1.ProtocolTCP,
+							Port:       int32(443),
+							NodePort:   int32(30667),
+						},
+						{
+							Name:       "http",
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromString("http"),
+							Port:       int32(80),
+							NodePort:   int32(30666),
+						},
+					},
+				},
+			},
+			assertion: func(t *testing.T, err error, got *corev1.Service) {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+				assert.Equal(t, got.Spec.ClusterIP, "10.1.1.10")
+				assert.Equal(t, got.Spec.Type, corev1.ServiceTypeLoadBalancer)
+				assert.Equal(t, got.Spec.HealthCheckNodePort, int32(43123))
+				expectedPorts := []corev1.ServicePort{
+					{
+						Name:       "http",
+						TargetPort: intstr.FromString("http"),
+						Protocol:   corev1.ProtocolTCP,
+						NodePort:   int32(30666),
+						Port:       int32(80),
+					},
+					{
+						Name:       "https",
+						TargetPort: intstr.FromString("https"),
+						Protocol:   corev1.ProtocolTCP,
+						NodePort:   int32(30667),
+						Port:       int32(443),
+					},
+				}
+				assert.Equal(t, expectedPorts, got.Spec.Ports)
+				assert.Equal(t, map[string]string{
+					"nginx.tsuru.io/app":           "nginx",
+					"nginx.tsuru.io/resource-name": "my-nginx",
+					"nginx.tsuru.io/new-label":     "v1",
+				}, got.Labels)
+				assert.Equal(t, map[string]string{"nginx.tsuru.io/new-annotation": "v1", "old-service-annotation": "v1"}, got.Annotations)
+			},
+			expectedEvents: []string{
+				"Normal ServiceUpdated service updated successfully",
+			},
+		},
+		{
+			name: "when updating the nginx service type, should discard nodeports when new service is clusterIP",
+			nginx: &v1alpha1.Nginx{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "extensions.tsuru.io/v1alpha1",
+					Kind:       "Nginx",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.NginxSpec{
+					Service: &v1alpha1.NginxService{
+						Type: corev1.ServiceTypeClusterIP,
+					},
+					TLS: []v1alpha1.NginxTLS{{SecretName: "my-secret"}},
+				},
+			},
+			service: &corev1.Service{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+					Kind:       "Service",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "my-nginx-service",
+					Namespace:   "default",
+					Annotations: map[string]string{},
+					Labels:      map[string]string{},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:                  corev1.ServiceTypeLoadBalancer,
+					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeCluster,
+					ClusterIP:             "10.1.1.10",
+					HealthCheckNodePort:   int32(43123),
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "https",
+							TargetPort: intstr.FromString("https"),
+							Protocol:   corev1.ProtocolTCP,
+							Port:       int32(443),
+							NodePort:   int32(30667),
+						},
+						{
+							Name:       "http",
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromString("http"),
+							Port:       int32(80),
+							NodePort:   int32(30666),
+						},
+					},
+				},
+			},
+			assertion: func(t *testing.T, err error, got *corev1.Service) {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+				assert.Equal(t, got.Spec.ClusterIP, "10.1.1.10")
+				assert.Equal(t, got.Spec.Type, corev1.ServiceTypeClusterIP)
+				assert.Equal(t, got.Spec.ExternalTrafficPolicy, corev1.ServiceExternalTrafficPolicyType(""))
+				assert.Equal(t, got.Spec.HealthCheckNodePort, int32(43123))
+				expectedPorts := []corev1.ServicePort{
+					{
+						Name:       "http",
+						TargetPort: intstr.FromString("http"),
+						Protocol:   corev1.ProtocolTCP,
+						Port:       int32(80),
+					},
+					{
+						Name:       "https",
+						TargetPort: intstr.FromString("https"),
+						Protocol:   corev1.ProtocolTCP,
+						Port:       int32(443),
+					},
+				}
+				assert.Equal(t, expectedPorts, got.Spec.Ports)
+			},
+			expectedEvents: []string{
+				"Normal ServiceUpdated service updated successfully",
+			},
+		},
+		{
+			name: "when updating the nginx service, should maintain other controller's annotations",
+			nginx: &v1alpha1.Nginx{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "extensions.tsuru.io/v1alpha1",
+					Kind:       "Nginx",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.NginxSpec{
+					Service: &v1alpha1.NginxService{
+						Type: corev1.ServiceTypeClusterIP,
+						Annotations: map[string]string{
+							"annotation-from-this-controller": "updated",
+						},
+					},
+				},
+			},
+			service: &corev1.Service{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+					Kind:       "Service",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx-service",
+					Namespace: "default",
+					Annotations: map[string]string{
+						"annotation-from-this-controller":    "please-update",
+						"annotation-from-another-controller": "please-keep-it",
+					},
+					Labels: map[string]string{},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:                  corev1.ServiceTypeLoadBalancer,
 					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeCluster,
 					ClusterIP:             "10.1.1.10",
 					HealthCheckNodePort:   int32(43123),
@@ -629,6 +1348,111 @@ func TestNginxReconciler_reconcileService(t *testing.T) {
 				"Normal ServiceUpdated service updated successfully",
 			},
 		},
+		{
+			name: "when service is already up to date, should not update it",
+			nginx: &v1alpha1.Nginx{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "extensions.tsuru.io/v1alpha1",
+					Kind:       "Nginx",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx",
+					Namespace: "default",
+				},
+			},
+			service: &corev1.Service{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+					Kind:       "Service",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx-service",
+					Namespace: "default",
+					Labels: map[string]string{
+						"nginx.tsuru.io/app":           "nginx",
+						"nginx.tsuru.io/resource-name": "my-nginx",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:      corev1.ServiceTypeClusterIP,
+					ClusterIP: "10.1.1.10",
+					Selector: map[string]string{
+						"nginx.tsuru.io/app":           "nginx",
+						"nginx.tsuru.io/resource-name": "my-nginx",
+					},
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "http",
+							TargetPort: intstr.FromString("http"),
+							Protocol:   corev1.ProtocolTCP,
+							Port:       int32(80),
+						},
+						{
+							Name:       "https",
+							TargetPort: intstr.FromString("https"),
+							Protocol:   corev1.ProtocolTCP,
+							Port:       int32(443),
+						},
+					},
+				},
+			},
+			assertion: func(t *testing.T, err error, got *corev1.Service) {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+				assert.Equal(t, "999", got.ResourceVersion)
+			},
+		},
+		{
+			name: "when the load balancer service has the external traffic policy defaulted by the API server, should not update it",
+			nginx: &v1alpha1.Nginx{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.NginxSpec{
+					Service: &v1alpha1.NginxService{Type: corev1.ServiceTypeLoadBalancer},
+				},
+			},
+			service: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx-service",
+					Namespace: "default",
+					Labels: map[string]string{
+						"nginx.tsuru.io/app":           "nginx",
+						"nginx.tsuru.io/resource-name": "my-nginx",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:                  corev1.ServiceTypeLoadBalancer,
+					ClusterIP:             "10.1.1.10",
+					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeCluster,
+					Selector: map[string]string{
+						"nginx.tsuru.io/app":           "nginx",
+						"nginx.tsuru.io/resource-name": "my-nginx",
+					},
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "http",
+							TargetPort: intstr.FromString("http"),
+							Protocol:   corev1.ProtocolTCP,
+							Port:       int32(80),
+							NodePort:   int32(30080),
+						},
+						{
+							Name:       "https",
+							TargetPort: intstr.FromString("https"),
+							Protocol:   corev1.ProtocolTCP,
+							Port:       int32(443),
+							NodePort:   int32(30443),
+						},
+					},
+				},
+			},
+			assertion: func(t *testing.T, err error, got *corev1.Service) {
+				assert.NoError(t, err)
+				assert.Equal(t, "999", got.ResourceVersion)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -963,79 +1787,971 @@ func TestNginxReconciler_reconcileIngress(t *testing.T) {
 				WithRuntimeObjects(resources...).
 				Build()
 
-			r := &NginxReconciler{Client: client}
-			err := r.reconcileIngress(context.TODO(), tt.nginx)
-			if tt.expectedError != "" {
-				assert.EqualError(t, err, tt.expectedError)
-				return
-			}
+			r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+			err := r.reconcileIngress(context.TODO(), tt.nginx)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			if tt.assert != nil {
+				tt.assert(t, client, tt.nginx)
+			}
+		})
+	}
+}
+
+func TestNginxReconciler_reconcileHorizontalPodAutoscaler(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			Autoscaling: &v1alpha1.NginxAutoscaling{MaxReplicas: 5},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		Build()
+
+	er := record.NewFakeRecorder(10)
+	r := &NginxReconciler{Client: client, EventRecorder: er}
+
+	require.NoError(t, r.reconcileHorizontalPodAutoscaler(context.TODO(), nginx))
+
+	var hpa autoscalingv2.HorizontalPodAutoscaler
+	err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &hpa)
+	require.NoError(t, err)
+	assert.Equal(t, int32(5), hpa.Spec.MaxReplicas)
+	assert.Equal(t, "my-nginx", hpa.Spec.ScaleTargetRef.Name)
+	assert.Equal(t, "Normal HorizontalPodAutoscalerCreated horizontal pod autoscaler created successfully", <-er.Events)
+
+	nginx.Spec.Autoscaling.MaxReplicas = 10
+	require.NoError(t, r.reconcileHorizontalPodAutoscaler(context.TODO(), nginx))
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &hpa)
+	require.NoError(t, err)
+	assert.Equal(t, int32(10), hpa.Spec.MaxReplicas)
+	assert.Equal(t, "Normal HorizontalPodAutoscalerUpdated horizontal pod autoscaler updated successfully", <-er.Events)
+
+	require.NoError(t, r.reconcileHorizontalPodAutoscaler(context.TODO(), nginx))
+	assert.Empty(t, er.Events)
+
+	nginx.Spec.Autoscaling = nil
+	require.NoError(t, r.reconcileHorizontalPodAutoscaler(context.TODO(), nginx))
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &hpa)
+	assert.True(t, errors.IsNotFound(err))
+	assert.Equal(t, "Normal HorizontalPodAutoscalerDeleted horizontal pod autoscaler deleted successfully", <-er.Events)
+
+	// NOTE: as in examples/autoscaling.yaml, where the user targets the Nginx
+	// scale subresource with a HorizontalPodAutoscaler of the same name.
+	require.NoError(t, client.Create(context.TODO(), &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "nginx.tsuru.io/v1alpha1", Kind: "Nginx", Name: "my-nginx"},
+			MaxReplicas:    10,
+		},
+	}))
+
+	require.NoError(t, r.reconcileHorizontalPodAutoscaler(context.TODO(), nginx))
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &hpa))
+	assert.Empty(t, er.Events)
+
+	nginx.Spec.Autoscaling = &v1alpha1.NginxAutoscaling{MaxReplicas: 5}
+	assert.EqualError(t, r.reconcileHorizontalPodAutoscaler(context.TODO(), nginx), `HorizontalPodAutoscaler "my-nginx" already exists and is not managed by this Nginx`)
+
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &hpa))
+	assert.Equal(t, int32(10), hpa.Spec.MaxReplicas)
+}
+
+func TestNginxReconciler_reconcileServiceAccount(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			ServiceAccount: &v1alpha1.NginxServiceAccount{
+				Annotations: map[string]string{"iam.gke.io/gcp-service-account": "nginx@project.iam.gserviceaccount.com"},
+			},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-nginx", Namespace: "default"},
+		}).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+
+	require.NoError(t, r.reconcileServiceAccount(context.TODO(), nginx))
+
+	var sa corev1.ServiceAccount
+	err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &sa)
+	require.NoError(t, err)
+	assert.Equal(t, "nginx@project.iam.gserviceaccount.com", sa.Annotations["iam.gke.io/gcp-service-account"])
+	assert.True(t, metav1.IsControlledBy(&sa, nginx))
+
+	sa.Annotations["kubernetes.io/enforce-mountable-secrets"] = "true"
+	require.NoError(t, client.Update(context.TODO(), &sa))
+
+	nginx.Spec.ServiceAccount.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry-credentials"}}
+	require.NoError(t, r.reconcileServiceAccount(context.TODO(), nginx))
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &sa)
+	require.NoError(t, err)
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "registry-credentials"}}, sa.ImagePullSecrets)
+	assert.Equal(t, "true", sa.Annotations["kubernetes.io/enforce-mountable-secrets"])
+
+	nginx.Spec.ServiceAccount = nil
+	require.NoError(t, r.reconcileServiceAccount(context.TODO(), nginx))
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &sa)
+	assert.True(t, errors.IsNotFound(err))
+
+	other := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-nginx", Namespace: "default"},
+		Spec:       v1alpha1.NginxSpec{ServiceAccount: &v1alpha1.NginxServiceAccount{}},
+	}
+	assert.EqualError(t, r.reconcileServiceAccount(context.TODO(), other), `ServiceAccount "other-nginx" already exists and is not managed by this Nginx`)
+
+	other.Spec.ServiceAccount = nil
+	require.NoError(t, r.reconcileServiceAccount(context.TODO(), other))
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "other-nginx", Namespace: "default"}, &sa))
+}
+
+func TestNginxReconciler_reconcilePodDisruptionBudget(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			PodDisruptionBudget: &v1alpha1.NginxPodDisruptionBudget{
+				MinAvailable: func(v intstr.IntOrString) *intstr.IntOrString { return &v }(intstr.FromInt(1)),
+			},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+
+	require.NoError(t, r.reconcilePodDisruptionBudget(context.TODO(), nginx))
+
+	var pdb policyv1.PodDisruptionBudget
+	err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &pdb)
+	require.NoError(t, err)
+	assert.Equal(t, func(v intstr.IntOrString) *intstr.IntOrString { return &v }(intstr.FromInt(1)), pdb.Spec.MinAvailable)
+	assert.Nil(t, pdb.Spec.MaxUnavailable)
+
+	nginx.Spec.PodDisruptionBudget = &v1alpha1.NginxPodDisruptionBudget{
+		MaxUnavailable: func(v intstr.IntOrString) *intstr.IntOrString { return &v }(intstr.FromString("50%")),
+	}
+	require.NoError(t, r.reconcilePodDisruptionBudget(context.TODO(), nginx))
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &pdb)
+	require.NoError(t, err)
+	assert.Nil(t, pdb.Spec.MinAvailable)
+	assert.Equal(t, func(v intstr.IntOrString) *intstr.IntOrString { return &v }(intstr.FromString("50%")), pdb.Spec.MaxUnavailable)
+
+	nginx.Spec.PodDisruptionBudget = nil
+	require.NoError(t, r.reconcilePodDisruptionBudget(context.TODO(), nginx))
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &pdb)
+	assert.True(t, errors.IsNotFound(err))
+
+	require.NoError(t, client.Create(context.TODO(), &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: func(v intstr.IntOrString) *intstr.IntOrString { return &v }(intstr.FromInt(2)),
+		},
+	}))
+
+	require.NoError(t, r.reconcilePodDisruptionBudget(context.TODO(), nginx))
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &pdb), "a PodDisruptionBudget not owned by the Nginx should be left alone")
+
+	nginx.Spec.PodDisruptionBudget = &v1alpha1.NginxPodDisruptionBudget{
+		MinAvailable: func(v intstr.IntOrString) *intstr.IntOrString { return &v }(intstr.FromInt(1)),
+	}
+	assert.EqualError(t, r.reconcilePodDisruptionBudget(context.TODO(), nginx), `PodDisruptionBudget "my-nginx" already exists and is not managed by this Nginx`)
+
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &pdb))
+	assert.Equal(t, func(v intstr.IntOrString) *intstr.IntOrString { return &v }(intstr.FromInt(2)), pdb.Spec.MinAvailable)
+}
+
+func TestNginxReconciler_reconcileNetworkPolicy(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			NetworkPolicy: &v1alpha1.NginxNetworkPolicy{},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+
+	require.NoError(t, r.reconcileNetworkPolicy(context.TODO(), nginx))
+
+	var np networkingv1.NetworkPolicy
+	err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &np)
+	require.NoError(t, err)
+	assert.Len(t, np.Spec.Egress, 1)
+
+	nginx.Spec.NetworkPolicy.Egress = []networkingv1.NetworkPolicyEgressRule{
+		{To: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}}}},
+	}
+	require.NoError(t, r.reconcileNetworkPolicy(context.TODO(), nginx))
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &np)
+	require.NoError(t, err)
+	assert.Len(t, np.Spec.Egress, 2)
+
+	nginx.Spec.NetworkPolicy = nil
+	require.NoError(t, r.reconcileNetworkPolicy(context.TODO(), nginx))
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &np)
+	assert.True(t, errors.IsNotFound(err))
+
+	require.NoError(t, client.Create(context.TODO(), &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}))
+
+	require.NoError(t, r.reconcileNetworkPolicy(context.TODO(), nginx))
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &np), "a NetworkPolicy not owned by the Nginx should be left alone")
+
+	nginx.Spec.NetworkPolicy = &v1alpha1.NginxNetworkPolicy{}
+	assert.EqualError(t, r.reconcileNetworkPolicy(context.TODO(), nginx), `NetworkPolicy "my-nginx" already exists and is not managed by this Nginx`)
+
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &np))
+	assert.Empty(t, np.Spec.Egress)
+}
+
+func TestNginxReconciler_reconcileInternalService(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			Service:         &v1alpha1.NginxService{Type: corev1.ServiceTypeLoadBalancer},
+			InternalService: &v1alpha1.NginxService{Annotations: map[string]string{"foo": "bar"}},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+
+	require.NoError(t, r.reconcileInternalService(context.TODO(), nginx))
+
+	var svc corev1.Service
+	err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-internal", Namespace: "default"}, &svc)
+	require.NoError(t, err)
+	assert.Equal(t, corev1.ServiceTypeClusterIP, svc.Spec.Type)
+	assert.Equal(t, "bar", svc.Annotations["foo"])
+
+	nginx.Spec.InternalService.Type = corev1.ServiceTypeNodePort
+	require.NoError(t, r.reconcileInternalService(context.TODO(), nginx))
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-internal", Namespace: "default"}, &svc)
+	require.NoError(t, err)
+	assert.Equal(t, corev1.ServiceTypeNodePort, svc.Spec.Type)
+
+	nginx.Spec.InternalService = nil
+	require.NoError(t, r.reconcileInternalService(context.TODO(), nginx))
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-internal", Namespace: "default"}, &svc)
+	assert.True(t, errors.IsNotFound(err))
+
+	require.NoError(t, client.Create(context.TODO(), &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx-internal", Namespace: "default"},
+	}))
+
+	require.NoError(t, r.reconcileInternalService(context.TODO(), nginx))
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-internal", Namespace: "default"}, &svc), "a Service not owned by the Nginx should be left alone")
+}
+
+func TestNginxReconciler_reconcileHeadlessService(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			Service: &v1alpha1.NginxService{Type: corev1.ServiceTypeLoadBalancer, Headless: true},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+
+	require.NoError(t, r.reconcileHeadlessService(context.TODO(), nginx))
+
+	var svc corev1.Service
+	err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-headless", Namespace: "default"}, &svc)
+	require.NoError(t, err)
+	assert.Equal(t, corev1.ClusterIPNone, svc.Spec.ClusterIP)
+	assert.Equal(t, corev1.ServiceTypeClusterIP, svc.Spec.Type)
+	assert.Equal(t, k8s.LabelsForNginx("my-nginx"), svc.Spec.Selector)
+
+	nginx.Spec.Service.Headless = false
+	require.NoError(t, r.reconcileHeadlessService(context.TODO(), nginx))
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-headless", Namespace: "default"}, &svc)
+	assert.True(t, errors.IsNotFound(err))
+
+	require.NoError(t, client.Create(context.TODO(), &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx-headless", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone},
+	}))
+
+	require.NoError(t, r.reconcileHeadlessService(context.TODO(), nginx))
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-headless", Namespace: "default"}, &svc), "a Service not owned by the Nginx should be left alone")
+
+	nginx.Spec.Service.Headless = true
+	assert.EqualError(t, r.reconcileHeadlessService(context.TODO(), nginx), `Service "my-nginx-headless" already exists and is not managed by this Nginx`)
+
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-headless", Namespace: "default"}, &svc))
+	assert.Empty(t, svc.Spec.Selector)
+}
+
+func TestNginxReconciler_reconcileHTTPRoute(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			Gateway: &v1alpha1.NginxGateway{
+				ParentRefs: []v1alpha1.NginxGatewayParentRef{{Name: "public"}},
+				Hostnames:  []string{"www.example.com"},
+			},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100), EnableGatewayAPI: true}
+
+	getRoute := func() (*unstructured.Unstructured, error) {
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(k8s.HTTPRouteGroupVersionKind)
+		err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, route)
+		return route, err
+	}
+
+	require.NoError(t, r.reconcileHTTPRoute(context.TODO(), nginx))
+	route, err := getRoute()
+	require.NoError(t, err)
+	hostnames, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+	assert.Equal(t, []string{"www.example.com"}, hostnames)
+
+	nginx.Spec.Gateway.Hostnames = []string{"www.example.com", "blog.example.com"}
+	require.NoError(t, r.reconcileHTTPRoute(context.TODO(), nginx))
+	route, err = getRoute()
+	require.NoError(t, err)
+	hostnames, _, _ = unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+	assert.Equal(t, []string{"www.example.com", "blog.example.com"}, hostnames)
+
+	// NOTE: fields not set by the operator, e.g. defaulted by the API server,
+	// must not trigger an update.
+	rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+	rules[0].(map[string]interface{})["timeouts"] = map[string]interface{}{"request": "10s"}
+	require.NoError(t, unstructured.SetNestedSlice(route.Object, rules, "spec", "rules"))
+	require.NoError(t, client.Update(context.TODO(), route))
+	route, err = getRoute()
+	require.NoError(t, err)
+
+	require.NoError(t, r.reconcileHTTPRoute(context.TODO(), nginx))
+	current, err := getRoute()
+	require.NoError(t, err)
+	assert.Equal(t, route.GetResourceVersion(), current.GetResourceVersion())
+
+	nginx.Spec.Gateway.Hostnames = nil
+	require.NoError(t, r.reconcileHTTPRoute(context.TODO(), nginx))
+	route, err = getRoute()
+	require.NoError(t, err)
+	_, found, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+	assert.False(t, found)
+
+	nginx.Spec.Gateway = nil
+	require.NoError(t, r.reconcileHTTPRoute(context.TODO(), nginx))
+	_, err = getRoute()
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestNginxReconciler_reconcileHTTPRoute_notOwned(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default", UID: "nginx-uid"},
+	}
+
+	route := &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{}}}
+	route.SetGroupVersionKind(k8s.HTTPRouteGroupVersionKind)
+	route.SetName("my-nginx")
+	route.SetNamespace("default")
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(route).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100), EnableGatewayAPI: true}
+
+	require.NoError(t, r.reconcileHTTPRoute(context.TODO(), nginx))
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, route))
+
+	nginx.Spec.Gateway = &v1alpha1.NginxGateway{ParentRefs: []v1alpha1.NginxGatewayParentRef{{Name: "public"}}}
+	assert.EqualError(t, r.reconcileHTTPRoute(context.TODO(), nginx), `HTTPRoute "my-nginx" already exists and is not managed by this Nginx`)
+}
+
+func TestNginxReconciler_reconcileServiceMonitor(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec: v1alpha1.NginxSpec{
+			Metrics: &v1alpha1.NginxMetrics{Enabled: true},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100), EnableServiceMonitor: true}
+
+	getServiceMonitor := func() (*unstructured.Unstructured, error) {
+		sm := &unstructured.Unstructured{}
+		sm.SetGroupVersionKind(k8s.ServiceMonitorGroupVersionKind)
+		err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, sm)
+		return sm, err
+	}
+
+	require.NoError(t, r.reconcileServiceMonitor(context.TODO(), nginx))
+	sm, err := getServiceMonitor()
+	require.NoError(t, err)
+	endpoints, _, _ := unstructured.NestedSlice(sm.Object, "spec", "endpoints")
+	assert.Equal(t, []interface{}{map[string]interface{}{"port": "metrics"}}, endpoints)
+
+	nginx.Spec.Metrics.ServiceMonitor = &v1alpha1.NginxServiceMonitor{Interval: "10s"}
+	require.NoError(t, r.reconcileServiceMonitor(context.TODO(), nginx))
+	sm, err = getServiceMonitor()
+	require.NoError(t, err)
+	endpoints, _, _ = unstructured.NestedSlice(sm.Object, "spec", "endpoints")
+	assert.Equal(t, []interface{}{map[string]interface{}{"port": "metrics", "interval": "10s"}}, endpoints)
+
+	nginx.Spec.Metrics.Enabled = false
+	require.NoError(t, r.reconcileServiceMonitor(context.TODO(), nginx))
+	_, err = getServiceMonitor()
+	assert.True(t, errors.IsNotFound(err))
+
+	sm = &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{}}}
+	sm.SetGroupVersionKind(k8s.ServiceMonitorGroupVersionKind)
+	sm.SetName("my-nginx")
+	sm.SetNamespace("default")
+	require.NoError(t, client.Create(context.TODO(), sm))
+
+	require.NoError(t, r.reconcileServiceMonitor(context.TODO(), nginx))
+	_, err = getServiceMonitor()
+	require.NoError(t, err, "a ServiceMonitor not owned by the Nginx should be left alone")
+
+	nginx.Spec.Metrics.Enabled = true
+	assert.EqualError(t, r.reconcileServiceMonitor(context.TODO(), nginx), `ServiceMonitor "my-nginx" already exists and is not managed by this Nginx`)
+}
+
+func TestNginxReconciler_reconcileStatus(t *testing.T) {
+	nginx := v1alpha1.Nginx{ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"}}
+
+	resources := []runtime.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-nginx",
+				Namespace: "default",
+				Labels: map[string]string{
+					"nginx.tsuru.io/app":           "nginx",
+					"nginx.tsuru.io/resource-name": "my-nginx",
+				},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: func(n int32) *int32 { return &n }(4),
+			},
+			Status: appsv1.DeploymentStatus{
+				Replicas:        int32(3),
+				ReadyReplicas:   int32(2),
+				UpdatedReplicas: int32(1),
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-nginx-service",
+				Namespace: "default",
+				Labels: map[string]string{
+					"nginx.tsuru.io/app":           "nginx",
+					"nginx.tsuru.io/resource-name": "my-nginx",
+				},
+			},
+		},
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-nginx",
+				Namespace: "default",
+				Labels: map[string]string{
+					"nginx.tsuru.io/app":           "nginx",
+					"nginx.tsuru.io/resource-name": "my-nginx",
+				},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-nginx-abc123",
+				Namespace: "default",
+				Labels: map[string]string{
+					"nginx.tsuru.io/app":           "nginx",
+					"nginx.tsuru.io/resource-name": "my-nginx",
+				},
+			},
+			Spec: corev1.PodSpec{NodeName: "node-1", Containers: []corev1.Container{{Name: "nginx", Image: "nginx:1.25.3"}}},
+			Status: corev1.PodStatus{
+				PodIP:     "10.0.0.10",
+				HostIP:    "192.168.0.1",
+				StartTime: &metav1.Time{Time: time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC)},
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+				},
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "nginx", Image: "docker.io/library/nginx:1.25.3", ImageID: "docker.io/library/nginx@sha256:deadbeef"},
+				},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-nginx-def456",
+				Namespace: "default",
+				Labels: map[string]string{
+					"nginx.tsuru.io/app":           "nginx",
+					"nginx.tsuru.io/resource-name": "my-nginx",
+				},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx:1.26.0"}}},
+		},
+		&nginx,
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(resources...).
+		Build()
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+	assert.NoError(t, r.refreshStatus(context.TODO(), &nginx))
+
+	var got v1alpha1.Nginx
+	err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got)
+	require.NoError(t, err)
+	assert.Len(t, got.Status.Conditions, 3)
+	got.Status.Conditions = nil
+	assert.Equal(t, v1alpha1.NginxStatus{
+		CurrentReplicas: int32(3),
+		ReadyReplicas:   int32(2),
+		UpdatedReplicas: int32(1),
+		DesiredReplicas: int32(4),
+		PodSelector:     "nginx.tsuru.io/app=nginx,nginx.tsuru.io/resource-name=my-nginx",
+		Deployments:     []v1alpha1.DeploymentStatus{{Name: "my-nginx"}},
+		Services:        []v1alpha1.ServiceStatus{{Name: "my-nginx-service"}},
+		Ingresses:       []v1alpha1.IngressStatus{{Name: "my-nginx"}},
+		Pods: []v1alpha1.PodStatus{
+			{
+				Name:      "my-nginx-abc123",
+				PodIP:     "10.0.0.10",
+				HostIP:    "192.168.0.1",
+				NodeName:  "node-1",
+				Ready:     true,
+				StartTime: &metav1.Time{Time: time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC).Local()},
+				Image:     "docker.io/library/nginx:1.25.3",
+				ImageID:   "docker.io/library/nginx@sha256:deadbeef",
+				Version:   "1.25.3",
+			},
+			{Name: "my-nginx-def456", Image: "nginx:1.26.0", Version: "1.26.0"},
+		},
+	}, got.Status)
+}
+
+func TestNginxReconciler_reconcileStatus_conditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		objects    []runtime.Object
+		assertions func(t *testing.T, conditions []metav1.Condition)
+	}{
+		{
+			name: "without deployment",
+			assertions: func(t *testing.T, conditions []metav1.Condition) {
+				require.Len(t, conditions, 1)
+				assert.Equal(t, "Available", conditions[0].Type)
+				assert.Equal(t, metav1.ConditionFalse, conditions[0].Status)
+				assert.Equal(t, "DeploymentNotFound", conditions[0].Reason)
+			},
+		},
+		{
+			name: "with deployment fully rolled out",
+			objects: []runtime.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "my-nginx",
+						Namespace: "default",
+						Labels: map[string]string{
+							"nginx.tsuru.io/app":           "nginx",
+							"nginx.tsuru.io/resource-name": "my-nginx",
+						},
+					},
+					Spec: appsv1.DeploymentSpec{
+						Replicas: func(n int32) *int32 { return &n }(2),
+					},
+					Status: appsv1.DeploymentStatus{
+						Replicas:        2,
+						UpdatedReplicas: 2,
+						ReadyReplicas:   2,
+						Conditions: []appsv1.DeploymentCondition{
+							{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue, Reason: "MinimumReplicasAvailable", Message: "Deployment has minimum availability."},
+							{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: "NewReplicaSetAvailable"},
+						},
+					},
+				},
+			},
+			assertions: func(t *testing.T, conditions []metav1.Condition) {
+				available := meta.FindStatusCondition(conditions, "Available")
+				require.NotNil(t, available)
+				assert.Equal(t, metav1.ConditionTrue, available.Status)
+				assert.Equal(t, "MinimumReplicasAvailable", available.Reason)
+
+				progressing := meta.FindStatusCondition(conditions, "Progressing")
+				require.NotNil(t, progressing)
+				assert.Equal(t, metav1.ConditionFalse, progressing.Status)
+				assert.Equal(t, "RolloutComplete", progressing.Reason)
+
+				degraded := meta.FindStatusCondition(conditions, "Degraded")
+				require.NotNil(t, degraded)
+				assert.Equal(t, metav1.ConditionFalse, degraded.Status)
+			},
+		},
+		{
+			name: "with pod failing to pull image",
+			objects: []runtime.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "my-nginx",
+						Namespace: "default",
+						Labels: map[string]string{
+							"nginx.tsuru.io/app":           "nginx",
+							"nginx.tsuru.io/resource-name": "my-nginx",
+						},
+					},
+					Status: appsv1.DeploymentStatus{
+						Replicas:        1,
+						UpdatedReplicas: 1,
+						Conditions: []appsv1.DeploymentCondition{
+							{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse, Reason: "MinimumReplicasUnavailable"},
+							{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: "ReplicaSetUpdated"},
+						},
+					},
+				},
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "my-nginx-abc123",
+						Namespace: "default",
+						Labels: map[string]string{
+							"nginx.tsuru.io/app":           "nginx",
+							"nginx.tsuru.io/resource-name": "my-nginx",
+						},
+					},
+					Status: corev1.PodStatus{
+						ContainerStatuses: []corev1.ContainerStatus{
+							{
+								Name: "nginx",
+								State: corev1.ContainerState{
+									Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"},
+								},
+							},
+						},
+					},
+				},
+			},
+			assertions: func(t *testing.T, conditions []metav1.Condition) {
+				available := meta.FindStatusCondition(conditions, "Available")
+				require.NotNil(t, available)
+				assert.Equal(t, metav1.ConditionFalse, available.Status)
+
+				progressing := meta.FindStatusCondition(conditions, "Progressing")
+				require.NotNil(t, progressing)
+				assert.Equal(t, metav1.ConditionTrue, progressing.Status)
+				assert.Equal(t, "RolloutInProgress", progressing.Reason)
+
+				degraded := meta.FindStatusCondition(conditions, "Degraded")
+				require.NotNil(t, degraded)
+				assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+				assert.Equal(t, "ImagePullBackOff", degraded.Reason)
+				assert.Equal(t, "pod my-nginx-abc123, container nginx: Back-off pulling image", degraded.Message)
+			},
+		},
+		{
+			name: "with pod killed for running out of memory",
+			objects: []runtime.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "my-nginx",
+						Namespace: "default",
+						Labels: map[string]string{
+							"nginx.tsuru.io/app":           "nginx",
+							"nginx.tsuru.io/resource-name": "my-nginx",
+						},
+					},
+					Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1},
+				},
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "my-nginx-abc123",
+						Namespace: "default",
+						Labels: map[string]string{
+							"nginx.tsuru.io/app":           "nginx",
+							"nginx.tsuru.io/resource-name": "my-nginx",
+						},
+					},
+					Status: corev1.PodStatus{
+						ContainerStatuses: []corev1.ContainerStatus{
+							{
+								Name: "nginx",
+								State: corev1.ContainerState{
+									Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off 10s restarting failed container"},
+								},
+								LastTerminationState: corev1.ContainerState{
+									Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137},
+								},
+							},
+						},
+					},
+				},
+			},
+			assertions: func(t *testing.T, conditions []metav1.Condition) {
+				degraded := meta.FindStatusCondition(conditions, "Degraded")
+				require.NotNil(t, degraded)
+				assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+				assert.Equal(t, "OOMKilled", degraded.Reason)
+				assert.Equal(t, "pod my-nginx-abc123, container nginx: terminated due to out of memory", degraded.Message)
+			},
+		},
+		{
+			name: "with deployment exceeding progress deadline",
+			objects: []runtime.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "my-nginx",
+						Namespace: "default",
+						Labels: map[string]string{
+							"nginx.tsuru.io/app":           "nginx",
+							"nginx.tsuru.io/resource-name": "my-nginx",
+						},
+					},
+					Status: appsv1.DeploymentStatus{
+						Replicas: 1,
+						Conditions: []appsv1.DeploymentCondition{
+							{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded", Message: "ReplicaSet has timed out progressing."},
+						},
+					},
+				},
+			},
+			assertions: func(t *testing.T, conditions []metav1.Condition) {
+				progressing := meta.FindStatusCondition(conditions, "Progressing")
+				require.NotNil(t, progressing)
+				assert.Equal(t, metav1.ConditionFalse, progressing.Status)
+				assert.Equal(t, "ProgressDeadlineExceeded", progressing.Reason)
+
+				degraded := meta.FindStatusCondition(conditions, "Degraded")
+				require.NotNil(t, degraded)
+				assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+				assert.Equal(t, "ProgressDeadlineExceeded", degraded.Reason)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nginx := &v1alpha1.Nginx{ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"}}
+
+			client := fake.NewClientBuilder().
+				WithScheme(newScheme()).
+				WithRuntimeObjects(append(tt.objects, nginx)...).
+				Build()
+
+			r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+			require.NoError(t, r.refreshStatus(context.TODO(), nginx))
+
+			var got v1alpha1.Nginx
+			err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got)
+			require.NoError(t, err)
+			tt.assertions(t, got.Status.Conditions)
+		})
+	}
+}
+
+func TestNginxReconciler_refreshStatus_degradedEvent(t *testing.T) {
+	podLabels := map[string]string{"nginx.tsuru.io/app": "nginx", "nginx.tsuru.io/resource-name": "my-nginx"}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx-abc123", Namespace: "default", Labels: podLabels},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "nginx", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}}},
+			},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(
+			&v1alpha1.Nginx{ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"}},
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default", Labels: podLabels}},
+			pod,
+		).
+		Build()
+
+	recorder := record.NewFakeRecorder(100)
+	r := &NginxReconciler{Client: client, EventRecorder: recorder}
+
+	refresh := func() {
+		var nginx v1alpha1.Nginx
+		require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &nginx))
+		require.NoError(t, r.refreshStatus(context.TODO(), &nginx))
+	}
+
+	refresh()
+	refresh()
 
-			assert.NoError(t, err)
-			if tt.assert != nil {
-				tt.assert(t, client, tt.nginx)
-			}
-		})
+	pod.Status.ContainerStatuses[0].State.Waiting = &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off 10s restarting failed container"}
+	require.NoError(t, client.Update(context.TODO(), pod))
+	refresh()
+
+	close(recorder.Events)
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
 	}
+
+	assert.Equal(t, []string{
+		"Warning ImagePullBackOff pod my-nginx-abc123, container nginx: Back-off pulling image",
+		"Warning CrashLoopBackOff pod my-nginx-abc123, container nginx: back-off 10s restarting failed container",
+	}, events)
 }
 
-func TestNginxReconciler_reconcileStatus(t *testing.T) {
-	nginx := v1alpha1.Nginx{ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"}}
+func TestNginxReconciler_refreshStatus_staleNginx(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+		Spec:       v1alpha1.NginxSpec{Image: "nginx:stable"},
+	}
 
-	resources := []runtime.Object{
-		&appsv1.Deployment{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "my-nginx",
-				Namespace: "default",
-				Labels: map[string]string{
-					"nginx.tsuru.io/app":           "nginx",
-					"nginx.tsuru.io/resource-name": "my-nginx",
-				},
-			},
-			Status: appsv1.DeploymentStatus{
-				Replicas: int32(3),
-			},
-		},
-		&corev1.Service{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "my-nginx-service",
-				Namespace: "default",
-				Labels: map[string]string{
-					"nginx.tsuru.io/app":           "nginx",
-					"nginx.tsuru.io/resource-name": "my-nginx",
-				},
-			},
-		},
-		&networkingv1.Ingress{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "my-nginx",
-				Namespace: "default",
-				Labels: map[string]string{
-					"nginx.tsuru.io/app":           "nginx",
-					"nginx.tsuru.io/resource-name": "my-nginx",
-				},
-			},
-		},
-		&nginx,
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(nginx).
+		Build()
+
+	var stale v1alpha1.Nginx
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &stale))
+
+	current := stale.DeepCopy()
+	current.Spec.Image = "nginx:1.25"
+	require.NoError(t, client.Update(context.TODO(), current))
+
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
+	require.NoError(t, r.refreshStatus(context.TODO(), &stale))
+
+	var got v1alpha1.Nginx
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got))
+	assert.Equal(t, "nginx:1.25", got.Spec.Image)
+	assert.NotNil(t, meta.FindStatusCondition(got.Status.Conditions, "Available"))
+}
+
+func TestNginxReconciler_refreshStatus_observedGeneration(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default", Generation: 3},
+		Spec:       v1alpha1.NginxSpec{Image: "nginx:stable"},
 	}
 
 	client := fake.NewClientBuilder().
 		WithScheme(newScheme()).
-		WithRuntimeObjects(resources...).
+		WithRuntimeObjects(nginx).
 		Build()
 
-	r := &NginxReconciler{Client: client}
-	assert.NoError(t, r.refreshStatus(context.TODO(), &nginx))
+	r := &NginxReconciler{Client: client, EventRecorder: record.NewFakeRecorder(100)}
 
 	var got v1alpha1.Nginx
-	err := client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got)
-	require.NoError(t, err)
-	assert.Equal(t, v1alpha1.NginxStatus{
-		CurrentReplicas: int32(3),
-		PodSelector:     "nginx.tsuru.io/app=nginx,nginx.tsuru.io/resource-name=my-nginx",
-		Deployments:     []v1alpha1.DeploymentStatus{{Name: "my-nginx"}},
-		Services:        []v1alpha1.ServiceStatus{{Name: "my-nginx-service"}},
-		Ingresses:       []v1alpha1.IngressStatus{{Name: "my-nginx"}},
-	}, got.Status)
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got))
+	require.NoError(t, r.refreshStatus(context.TODO(), &got))
+	assert.Equal(t, int64(3), got.Status.ObservedGeneration)
+
+	got.Generation = 4
+	got.Annotations = map[string]string{pausedAnnotationKey: "true"}
+	require.NoError(t, r.refreshStatus(context.TODO(), &got))
+	assert.Equal(t, int64(3), got.Status.ObservedGeneration)
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	rl := newRateLimiter(time.Second, 5*time.Second)
+
+	broken := reconcile.Request{NamespacedName: types.NamespacedName{Name: "broken", Namespace: "default"}}
+	var delays []time.Duration
+	for i := 0; i < 5; i++ {
+		delays = append(delays, rl.When(broken))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, delays)
+
+	other := reconcile.Request{NamespacedName: types.NamespacedName{Name: "other", Namespace: "default"}}
+	assert.Equal(t, time.Second, rl.When(other))
+
+	rl.Forget(broken)
+	assert.Equal(t, time.Second, rl.When(broken))
+
+	rl = newRateLimiter(0, 0)
+	for i := 0; i < 20; i++ {
+		rl.When(broken)
+	}
+	assert.Equal(t, defaultRetryMaxDelay, rl.When(broken))
+}
+
+func TestSetDaemonSetStatusConditions(t *testing.T) {
+	nginx := &v1alpha1.Nginx{ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default", Generation: 2}}
+
+	var status v1alpha1.NginxStatus
+	setDaemonSetStatusConditions(&status, nginx, nil, nil)
+	require.Len(t, status.Conditions, 1)
+	assert.Equal(t, "DaemonSetNotFound", status.Conditions[0].Reason)
+
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default", Generation: 3},
+		Status: appsv1.DaemonSetStatus{
+			ObservedGeneration:     3,
+			DesiredNumberScheduled: 3,
+			CurrentNumberScheduled: 3,
+			UpdatedNumberScheduled: 2,
+			NumberReady:            3,
+			NumberAvailable:        3,
+		},
+	}
+
+	status = v1alpha1.NginxStatus{}
+	setDaemonSetStatusConditions(&status, nginx, ds, nil)
+
+	available := meta.FindStatusCondition(status.Conditions, "Available")
+	require.NotNil(t, available)
+	assert.Equal(t, metav1.ConditionTrue, available.Status)
+	assert.Equal(t, int64(2), available.ObservedGeneration)
+
+	progressing := meta.FindStatusCondition(status.Conditions, "Progressing")
+	require.NotNil(t, progressing)
+	assert.Equal(t, metav1.ConditionTrue, progressing.Status)
+	assert.Equal(t, "2 of 3 pods are updated and 3 are ready", progressing.Message)
+
+	degraded := meta.FindStatusCondition(status.Conditions, "Degraded")
+	require.NotNil(t, degraded)
+	assert.Equal(t, metav1.ConditionFalse, degraded.Status)
 }
 
 func TestNginxReconciler_shouldManageNginx(t *testing.T) {
@@ -1096,6 +2812,113 @@ func TestNginxReconciler_shouldManageNginx(t *testing.T) {
 	}
 }
 
+func TestShouldUpdateService_sessionAffinity(t *testing.T) {
+	current := &corev1.Service{Spec: corev1.ServiceSpec{SessionAffinity: corev1.ServiceAffinityNone}}
+	assert.False(t, shouldUpdateService(current, &corev1.Service{}))
+
+	new := &corev1.Service{Spec: corev1.ServiceSpec{SessionAffinity: corev1.ServiceAffinityClientIP}}
+	assert.True(t, shouldUpdateService(current, new))
+
+	current = &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			SessionAffinity: corev1.ServiceAffinityClientIP,
+			SessionAffinityConfig: &corev1.SessionAffinityConfig{
+				ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: func(n int32) *int32 { return &n }(10800)},
+			},
+		},
+	}
+	assert.False(t, shouldUpdateService(current, new))
+
+	new.Spec.SessionAffinityConfig = &corev1.SessionAffinityConfig{
+		ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: func(n int32) *int32 { return &n }(600)},
+	}
+	assert.True(t, shouldUpdateService(current, new))
+}
+
+func TestShouldUpdateService_externalTrafficPolicy(t *testing.T) {
+	current := &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			Type:                  corev1.ServiceTypeLoadBalancer,
+			ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeCluster,
+		},
+	}
+	assert.False(t, shouldUpdateService(current, &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer}}))
+
+	new := &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			Type:                  corev1.ServiceTypeLoadBalancer,
+			ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+		},
+	}
+	assert.True(t, shouldUpdateService(current, new))
+
+	current = &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}}
+	assert.False(t, shouldUpdateService(current, &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}}))
+}
+
+func TestShouldUpdateService_ipFamilies(t *testing.T) {
+	singleStack, dualStack := corev1.IPFamilyPolicySingleStack, corev1.IPFamilyPolicyPreferDualStack
+	current := &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			IPFamilies:     []corev1.IPFamily{corev1.IPv4Protocol},
+			IPFamilyPolicy: &singleStack,
+		},
+	}
+	assert.False(t, shouldUpdateService(current, &corev1.Service{}))
+
+	new := &corev1.Service{Spec: corev1.ServiceSpec{IPFamilyPolicy: &dualStack}}
+	assert.True(t, shouldUpdateService(current, new))
+
+	new = &corev1.Service{Spec: corev1.ServiceSpec{IPFamilies: []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol}}}
+	assert.True(t, shouldUpdateService(current, new))
+}
+
+func TestPreserveACMEChallengePaths(t *testing.T) {
+	backend := func(name string) networkingv1.IngressBackend {
+		return networkingv1.IngressBackend{
+			Service: &networkingv1.IngressServiceBackend{
+				Name: name,
+				Port: networkingv1.ServiceBackendPort{Number: 8089},
+			},
+		}
+	}
+
+	rule := func(host string, paths ...networkingv1.HTTPIngressPath) networkingv1.IngressRule {
+		return networkingv1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{Paths: paths},
+			},
+		}
+	}
+
+	nginxPath := networkingv1.HTTPIngressPath{Path: "/", Backend: backend("my-nginx-service")}
+	challengePath := networkingv1.HTTPIngressPath{Path: "/.well-known/acme-challenge/token", Backend: backend("cm-acme-http-solver-abcde")}
+
+	current := &networkingv1.Ingress{
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{
+				rule("www.example.com", nginxPath, challengePath),
+				rule("old.example.com", challengePath),
+			},
+		},
+	}
+
+	new := &networkingv1.Ingress{
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{
+				rule("www.example.com", nginxPath),
+			},
+		},
+	}
+
+	preserveACMEChallengePaths(current, new)
+	assert.Equal(t, []networkingv1.IngressRule{
+		rule("www.example.com", challengePath, nginxPath),
+		rule("old.example.com", challengePath),
+	}, new.Spec.Rules)
+}
+
 func TestShouldUpdateIngress(t *testing.T) {
 	tests := []struct {
 		current  *networkingv1.Ingress
@@ -1250,6 +3073,14 @@ func TestListServices(t *testing.T) {
 					"nginx.tsuru.io/resource-name": "my-nginx",
 				},
 			},
+			Spec: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeLoadBalancer,
+				ClusterIP: "10.96.0.10",
+				Ports: []corev1.ServicePort{
+					{Name: "http", Port: 80, NodePort: 30080},
+					{Name: "https", Port: 443, NodePort: 30443},
+				},
+			},
 			Status: corev1.ServiceStatus{
 				LoadBalancer: corev1.LoadBalancerStatus{
 					Ingress: []corev1.LoadBalancerIngress{
@@ -1318,8 +3149,13 @@ func TestListServices(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []v1alpha1.ServiceStatus{
 		{
-			Name: "my-nginx-service",
-			IPs:  []string{"1.1.1.1", "1.1.1.2"},
+			Name:      "my-nginx-service",
+			IPs:       []string{"1.1.1.1", "1.1.1.2"},
+			ClusterIP: "10.96.0.10",
+			NodePorts: []v1alpha1.ServiceNodePort{
+				{Name: "http", Port: 80, NodePort: 30080},
+				{Name: "https", Port: 443, NodePort: 30443},
+			},
 		},
 		{
 			Name:      "my-nginx-service-hostname",
@@ -1332,6 +3168,195 @@ func TestListServices(t *testing.T) {
 	}, svcs)
 }
 
+func TestNginxReconciler_finalizeNginx(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "my-nginx",
+			Namespace:         "default",
+			DeletionTimestamp: func(t metav1.Time) *metav1.Time { return &t }(metav1.Now()),
+			Finalizers:        []string{"nginx.tsuru.io/finalizer"},
+		},
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx-service", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(nginx, service).
+		Build()
+
+	er := record.NewFakeRecorder(10)
+	r := &NginxReconciler{Client: client, EventRecorder: er}
+
+	result, err := r.finalizeNginx(context.TODO(), nginx)
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{RequeueAfter: 5 * time.Second}, result)
+	assert.Equal(t, "Normal ServiceDeleted service deleted successfully", <-er.Events)
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-service", Namespace: "default"}, &corev1.Service{})
+	assert.True(t, errors.IsNotFound(err))
+
+	var got v1alpha1.Nginx
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got))
+	assert.Equal(t, []string{"nginx.tsuru.io/finalizer"}, got.Finalizers)
+
+	result, err = r.finalizeNginx(context.TODO(), &got)
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, result)
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got)
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestNginxReconciler_finalizeNginx_orphanService(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "my-nginx",
+			Namespace:         "default",
+			UID:               "nginx-uid",
+			DeletionTimestamp: func(t metav1.Time) *metav1.Time { return &t }(metav1.Now()),
+			Finalizers:        []string{"nginx.tsuru.io/finalizer"},
+		},
+		Spec: v1alpha1.NginxSpec{
+			Service:         &v1alpha1.NginxService{Type: corev1.ServiceTypeLoadBalancer},
+			InternalService: &v1alpha1.NginxService{Type: corev1.ServiceTypeLoadBalancer},
+			DeletionPolicy:  &v1alpha1.NginxDeletionPolicy{Service: v1alpha1.DeletionPolicyOrphan},
+		},
+	}
+
+	service := k8s.NewService(nginx)
+	service.OwnerReferences = append(service.OwnerReferences, metav1.OwnerReference{APIVersion: "example.com/v1", Kind: "App", Name: "web", UID: "app-uid"})
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(nginx, service, k8s.NewInternalService(nginx)).
+		Build()
+
+	er := record.NewFakeRecorder(10)
+	r := &NginxReconciler{Client: client, EventRecorder: er}
+
+	result, err := r.finalizeNginx(context.TODO(), nginx)
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, result)
+	assert.Equal(t, "Normal ServiceOrphaned service orphaned successfully", <-er.Events)
+
+	var got corev1.Service
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-service", Namespace: "default"}, &got))
+	assert.Equal(t, []metav1.OwnerReference{{APIVersion: "example.com/v1", Kind: "App", Name: "web", UID: "app-uid"}}, got.OwnerReferences)
+
+	assert.Equal(t, "Normal ServiceOrphaned service orphaned successfully", <-er.Events)
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-internal", Namespace: "default"}, &got))
+	assert.Empty(t, got.OwnerReferences)
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &v1alpha1.Nginx{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestNginxReconciler_finalizeNginx_internalService(t *testing.T) {
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "my-nginx",
+			Namespace:         "default",
+			DeletionTimestamp: func(t metav1.Time) *metav1.Time { return &t }(metav1.Now()),
+			Finalizers:        []string{"nginx.tsuru.io/finalizer"},
+		},
+		Spec: v1alpha1.NginxSpec{
+			InternalService: &v1alpha1.NginxService{Type: corev1.ServiceTypeLoadBalancer},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(nginx, k8s.NewInternalService(nginx)).
+		Build()
+
+	er := record.NewFakeRecorder(10)
+	r := &NginxReconciler{Client: client, EventRecorder: er}
+
+	result, err := r.finalizeNginx(context.TODO(), nginx)
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{RequeueAfter: 5 * time.Second}, result)
+	assert.Equal(t, "Normal ServiceDeleted service deleted successfully", <-er.Events)
+
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx-internal", Namespace: "default"}, &corev1.Service{})
+	assert.True(t, errors.IsNotFound(err))
+
+	var got v1alpha1.Nginx
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got))
+
+	result, err = r.finalizeNginx(context.TODO(), &got)
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, result)
+}
+
+func TestIsNginxPaused(t *testing.T) {
+	assert.False(t, isNginxPaused(&v1alpha1.Nginx{}))
+	assert.False(t, isNginxPaused(&v1alpha1.Nginx{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"nginx.tsuru.io/paused": "false"}}}))
+	assert.True(t, isNginxPaused(&v1alpha1.Nginx{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"nginx.tsuru.io/paused": "true"}}}))
+}
+
+func TestIsLoadBalancerAddressPending(t *testing.T) {
+	tests := []struct {
+		name     string
+		nginx    v1alpha1.Nginx
+		expected bool
+	}{
+		{
+			name:  "without service spec",
+			nginx: v1alpha1.Nginx{},
+		},
+		{
+			name: "with cluster ip service",
+			nginx: v1alpha1.Nginx{
+				Spec:   v1alpha1.NginxSpec{Service: &v1alpha1.NginxService{Type: corev1.ServiceTypeClusterIP}},
+				Status: v1alpha1.NginxStatus{Services: []v1alpha1.ServiceStatus{{Name: "my-nginx-service"}}},
+			},
+		},
+		{
+			name: "with load balancer service without address",
+			nginx: v1alpha1.Nginx{
+				Spec:   v1alpha1.NginxSpec{Service: &v1alpha1.NginxService{Type: corev1.ServiceTypeLoadBalancer}},
+				Status: v1alpha1.NginxStatus{Services: []v1alpha1.ServiceStatus{{Name: "my-nginx-service"}}},
+			},
+			expected: true,
+		},
+		{
+			name: "with load balancer service not listed yet",
+			nginx: v1alpha1.Nginx{
+				Spec: v1alpha1.NginxSpec{Service: &v1alpha1.NginxService{Type: corev1.ServiceTypeLoadBalancer}},
+			},
+			expected: true,
+		},
+		{
+			name: "with load balancer service with hostname and headless service",
+			nginx: v1alpha1.Nginx{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-nginx"},
+				Spec:       v1alpha1.NginxSpec{Service: &v1alpha1.NginxService{Type: corev1.ServiceTypeLoadBalancer, Headless: true}},
+				Status: v1alpha1.NginxStatus{Services: []v1alpha1.ServiceStatus{
+					{Name: "my-nginx-service", Hostnames: []string{"lb.example.com"}},
+					{Name: "my-nginx-headless"},
+				}},
+			},
+		},
+		{
+			name: "with load balancer service with hostname",
+			nginx: v1alpha1.Nginx{
+				Spec:   v1alpha1.NginxSpec{Service: &v1alpha1.NginxService{Type: corev1.ServiceTypeLoadBalancer}},
+				Status: v1alpha1.NginxStatus{Services: []v1alpha1.ServiceStatus{{Name: "my-nginx-service", Hostnames: []string{"lb.example.com"}}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isLoadBalancerAddressPending(&tt.nginx))
+		})
+	}
+}
+
 func TestListIngress(t *testing.T) {
 	nginx := &v1alpha1.Nginx{ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"}}
 
@@ -1427,6 +3452,123 @@ func TestListIngress(t *testing.T) {
 	}, svcs)
 }
 
+func TestNginxReconciler_nginxesForConfigMap(t *testing.T) {
+	resources := []runtime.Object{
+		&v1alpha1.Nginx{
+			ObjectMeta: metav1.ObjectMeta{Name: "with-config", Namespace: "default"},
+			Spec: v1alpha1.NginxSpec{
+				Config: &v1alpha1.ConfigRef{Kind: v1alpha1.ConfigKindConfigMap, Name: "my-config"},
+			},
+		},
+		&v1alpha1.Nginx{
+			ObjectMeta: metav1.ObjectMeta{Name: "with-extra-files", Namespace: "default"},
+			Spec: v1alpha1.NginxSpec{
+				ExtraFiles: &v1alpha1.FilesRef{Name: "my-config"},
+			},
+		},
+		&v1alpha1.Nginx{
+			ObjectMeta: metav1.ObjectMeta{Name: "with-env-from", Namespace: "default"},
+			Spec: v1alpha1.NginxSpec{
+				PodTemplate: v1alpha1.NginxPodTemplateSpec{
+					EnvFrom: []corev1.EnvFromSource{
+						{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "my-config"}}},
+					},
+				},
+			},
+		},
+		&v1alpha1.Nginx{
+			ObjectMeta: metav1.ObjectMeta{Name: "with-log-shipper", Namespace: "default"},
+			Spec: v1alpha1.NginxSpec{
+				Logging: &v1alpha1.NginxLogging{Sidecar: &v1alpha1.NginxLoggingSidecar{ConfigMapName: "my-config"}},
+			},
+		},
+		&v1alpha1.Nginx{
+			ObjectMeta: metav1.ObjectMeta{Name: "with-inline-config", Namespace: "default"},
+			Spec: v1alpha1.NginxSpec{
+				Config: &v1alpha1.ConfigRef{Kind: v1alpha1.ConfigKindInline, Value: "events {}"},
+			},
+		},
+		&v1alpha1.Nginx{
+			ObjectMeta: metav1.ObjectMeta{Name: "with-config", Namespace: "other"},
+			Spec: v1alpha1.NginxSpec{
+				Config: &v1alpha1.ConfigRef{Kind: v1alpha1.ConfigKindConfigMap, Name: "my-config"},
+			},
+		},
+	}
+
+	r := &NginxReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(newScheme()).
+			WithRuntimeObjects(resources...).
+			Build(),
+		Log: ctrl.Log.WithName("test"),
+	}
+
+	requests := r.nginxesForConfigMap(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-config", Namespace: "default"}})
+	assert.Equal(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: "with-config", Namespace: "default"}},
+		{NamespacedName: types.NamespacedName{Name: "with-env-from", Namespace: "default"}},
+		{NamespacedName: types.NamespacedName{Name: "with-extra-files", Namespace: "default"}},
+		{NamespacedName: types.NamespacedName{Name: "with-log-shipper", Namespace: "default"}},
+	}, requests)
+
+	requests = r.nginxesForConfigMap(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unused", Namespace: "default"}})
+	assert.Empty(t, requests)
+}
+
+func TestNginxReconciler_nginxesForSecret(t *testing.T) {
+	resources := []runtime.Object{
+		&v1alpha1.Nginx{
+			ObjectMeta: metav1.ObjectMeta{Name: "with-tls", Namespace: "default"},
+			Spec: v1alpha1.NginxSpec{
+				TLS: []v1alpha1.NginxTLS{{SecretName: "other-cert"}, {SecretName: "my-cert"}},
+			},
+		},
+		&v1alpha1.Nginx{
+			ObjectMeta: metav1.ObjectMeta{Name: "with-client-ca", Namespace: "default"},
+			Spec: v1alpha1.NginxSpec{
+				TLS: []v1alpha1.NginxTLS{{SecretName: "other-cert", ClientAuth: &v1alpha1.NginxTLSClientAuth{CASecretName: "my-ca"}}},
+			},
+		},
+		&v1alpha1.Nginx{
+			ObjectMeta: metav1.ObjectMeta{Name: "with-env-secret", Namespace: "default"},
+			Spec: v1alpha1.NginxSpec{
+				PodTemplate: v1alpha1.NginxPodTemplateSpec{
+					Env: []corev1.EnvVar{{Name: "API_KEY", ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "my-api-key"}, Key: "key"},
+					}}},
+				},
+			},
+		},
+		&v1alpha1.Nginx{
+			ObjectMeta: metav1.ObjectMeta{Name: "without-tls", Namespace: "default"},
+		},
+	}
+
+	r := &NginxReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(newScheme()).
+			WithRuntimeObjects(resources...).
+			Build(),
+		Log: ctrl.Log.WithName("test"),
+	}
+
+	requests := r.nginxesForSecret(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-cert", Namespace: "default"}})
+	assert.Equal(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: "with-tls", Namespace: "default"}},
+	}, requests)
+
+	requests = r.nginxesForSecret(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-ca", Namespace: "default"}})
+	assert.Equal(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: "with-client-ca", Namespace: "default"}},
+	}, requests)
+
+	requests = r.nginxesForSecret(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-api-key", Namespace: "default"}})
+	assert.Equal(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: "with-env-secret", Namespace: "default"}},
+	}, requests)
+}
+
 func newScheme() *runtime.Scheme {
 	scheme := runtime.NewScheme()
 	clientgoscheme.AddToScheme(scheme)
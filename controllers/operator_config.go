@@ -0,0 +1,262 @@
+// Copyright 2026 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	nginxv1alpha1 "github.com/tsuru/nginx-operator/api/v1alpha1"
+)
+
+// OperatorConfig holds the operator-wide defaults of the Nginx resources. The
+// fields set on a Nginx take precedence over them.
+type OperatorConfig struct {
+	// Image is the nginx container image used when the Nginx doesn't set one.
+	Image string `json:"image,omitempty"`
+
+	// Resources are the nginx container resources used when the Nginx sets
+	// neither requests nor limits.
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Healthcheck customizes the probes of the nginx container when the Nginx
+	// doesn't set its own.
+	Healthcheck *nginxv1alpha1.NginxHealthcheck `json:"healthcheck,omitempty"`
+
+	// RegistryMirror is the registry the nginx, metrics exporter and log
+	// shipper images are pulled from instead of their own, e.g. with
+	// "mirror.example.com" the "nginx:stable" image is pulled as
+	// "mirror.example.com/library/nginx:stable". The sidecar images left to
+	// the operator defaults are not rewritten.
+	RegistryMirror string `json:"registryMirror,omitempty"`
+
+	// WatchNamespaces limits the observed Nginx resources to the given
+	// namespaces, unless the --namespace flag is set. Changing it requires
+	// restarting the operator.
+	WatchNamespaces []string `json:"watchNamespaces,omitempty"`
+}
+
+// ParseOperatorConfig parses the operator config from YAML (or JSON),
+// rejecting unknown fields.
+func ParseOperatorConfig(data []byte) (*OperatorConfig, error) {
+	var config OperatorConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// ApplyDefaults fills the unset fields of the Nginx spec with the operator
+// defaults. It must run before NginxSpec.SetDefaults, so the image resolves
+// to the Nginx one, then the operator config one and finally
+// DefaultNginxImage.
+func (c *OperatorConfig) ApplyDefaults(spec *nginxv1alpha1.NginxSpec) {
+	if c == nil {
+		return
+	}
+
+	if spec.Image == "" {
+		spec.Image = c.Image
+	}
+
+	if len(spec.Resources.Requests) == 0 && len(spec.Resources.Limits) == 0 {
+		spec.Resources = *c.Resources.DeepCopy()
+	}
+
+	if spec.Healthcheck == nil && c.Healthcheck != nil {
+		spec.Healthcheck = c.Healthcheck.DeepCopy()
+	}
+
+	if c.RegistryMirror == "" {
+		return
+	}
+
+	spec.SetDefaults()
+	spec.Image = mirrorImage(spec.Image, c.RegistryMirror)
+
+	if m := spec.Metrics; m != nil {
+		m.Image = mirrorImage(m.Image, c.RegistryMirror)
+	}
+
+	if l := spec.Logging; l != nil && l.Sidecar != nil {
+		l.Sidecar.Image = mirrorImage(l.Sidecar.Image, c.RegistryMirror)
+	}
+}
+
+// mirrorImage replaces the registry of the image by the mirror one. Images
+// without registry are from Docker Hub.
+func mirrorImage(image, mirror string) string {
+	mirror = strings.TrimSuffix(mirror, "/")
+	if image == "" || strings.HasPrefix(image, mirror+"/") {
+		return image
+	}
+
+	name := image
+	if i := strings.Index(image, "/"); i >= 0 && (strings.ContainsAny(image[:i], ".:") || image[:i] == "localhost") {
+		name = image[i+1:]
+	} else if i < 0 {
+		name = "library/" + image
+	}
+
+	return mirror + "/" + name
+}
+
+// OperatorConfigLoader keeps the operator config loaded from a file (e.g. a
+// mounted ConfigMap), reloading it when the file changes. Every Nginx gets
+// reconciled again once a new config is loaded.
+type OperatorConfigLoader struct {
+	path string
+	log  logr.Logger
+
+	mu     sync.RWMutex
+	data   []byte
+	config *OperatorConfig
+
+	changes chan event.GenericEvent
+}
+
+// NewOperatorConfigLoader loads the operator config from the given file.
+func NewOperatorConfigLoader(path string, log logr.Logger) (*OperatorConfigLoader, error) {
+	l := &OperatorConfigLoader{
+		path:    path,
+		log:     log,
+		changes: make(chan event.GenericEvent, 1),
+	}
+
+	if _, err := l.load(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Config returns the last operator config successfully loaded, or nil when
+// there is no loader.
+func (l *OperatorConfigLoader) Config() *OperatorConfig {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.config
+}
+
+// Start watches the config file until the context is done. It implements the
+// manager.Runnable interface.
+func (l *OperatorConfigLoader) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// NOTE: watching the directory rather than the file, as the files of
+	// mounted ConfigMaps are replaced (through symlinks) rather than written.
+	if err = watcher.Add(filepath.Dir(l.path)); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			changed, err := l.load()
+			if err != nil {
+				l.log.Error(err, "Unable to reload the operator config, keeping the previous one", "path", l.path)
+				continue
+			}
+
+			if !changed {
+				continue
+			}
+
+			l.log.Info("Operator config reloaded", "path", l.path)
+
+			select {
+			case l.changes <- event.GenericEvent{Object: &nginxv1alpha1.Nginx{}}:
+			default: // a reconcile of every Nginx is already pending
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			l.log.Error(err, "Operator config watch error", "path", l.path)
+		}
+	}
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface,
+// the config is kept up to date even on replicas waiting for the leadership.
+func (l *OperatorConfigLoader) NeedLeaderElection() bool {
+	return false
+}
+
+// load reads the config file, returning whether its content changed.
+func (l *OperatorConfigLoader) load() (bool, error) {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the operator config: %w", err)
+	}
+
+	l.mu.RLock()
+	unchanged := l.config != nil && bytes.Equal(data, l.data)
+	l.mu.RUnlock()
+
+	if unchanged {
+		return false, nil
+	}
+
+	config, err := ParseOperatorConfig(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse the operator config: %w", err)
+	}
+
+	l.mu.Lock()
+	l.data, l.config = data, config
+	l.mu.Unlock()
+
+	return true, nil
+}
+
+// allNginxes returns a reconcile request for every Nginx.
+func (r *NginxReconciler) allNginxes(o client.Object) []reconcile.Request {
+	var nginxList nginxv1alpha1.NginxList
+	if err := r.Client.List(context.Background(), &nginxList); err != nil {
+		r.Log.Error(err, "Unable to list Nginx resources")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, n := range nginxList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&n),
+		})
+	}
+
+	return requests
+}
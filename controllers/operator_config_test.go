@@ -0,0 +1,262 @@
+// Copyright 2026 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/tsuru/nginx-operator/api/v1alpha1"
+)
+
+func TestOperatorConfig_ApplyDefaults(t *testing.T) {
+	config := &OperatorConfig{
+		Image: "nginx:1.25",
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+		},
+		Healthcheck: &v1alpha1.NginxHealthcheck{Path: "/healthz"},
+	}
+
+	tests := map[string]struct {
+		config   *OperatorConfig
+		spec     v1alpha1.NginxSpec
+		expected func(spec v1alpha1.NginxSpec) v1alpha1.NginxSpec
+	}{
+		"without config, should keep the spec": {
+			spec:     v1alpha1.NginxSpec{},
+			expected: func(spec v1alpha1.NginxSpec) v1alpha1.NginxSpec { return spec },
+		},
+
+		"with an empty spec, should fill it with the defaults": {
+			config: config,
+			spec:   v1alpha1.NginxSpec{},
+			expected: func(spec v1alpha1.NginxSpec) v1alpha1.NginxSpec {
+				spec.Image = "nginx:1.25"
+				spec.Resources = config.Resources
+				spec.Healthcheck = &v1alpha1.NginxHealthcheck{Path: "/healthz"}
+				return spec
+			},
+		},
+
+		"with the fields set on the spec, should keep them": {
+			config: config,
+			spec: v1alpha1.NginxSpec{
+				Image: "nginx:stable",
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+				},
+				Healthcheck: &v1alpha1.NginxHealthcheck{Path: "/ping"},
+			},
+			expected: func(spec v1alpha1.NginxSpec) v1alpha1.NginxSpec { return spec },
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			spec := tt.spec.DeepCopy()
+			tt.config.ApplyDefaults(spec)
+			assert.Equal(t, tt.expected(tt.spec), *spec)
+		})
+	}
+}
+
+func TestOperatorConfig_ApplyDefaults_registryMirror(t *testing.T) {
+	spec := v1alpha1.NginxSpec{
+		Metrics: &v1alpha1.NginxMetrics{Image: "quay.io/acme/exporter:1.0"},
+		Logging: &v1alpha1.NginxLogging{Sidecar: &v1alpha1.NginxLoggingSidecar{}},
+	}
+
+	(&OperatorConfig{RegistryMirror: "mirror.example.com"}).ApplyDefaults(&spec)
+
+	assert.Equal(t, "mirror.example.com/library/nginx:latest", spec.Image)
+	assert.Equal(t, "mirror.example.com/acme/exporter:1.0", spec.Metrics.Image)
+	assert.Empty(t, spec.Logging.Sidecar.Image)
+}
+
+func TestMirrorImage(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{image: "", expected: ""},
+		{image: "nginx", expected: "mirror.example.com/library/nginx"},
+		{image: "nginx:1.25@sha256:abc", expected: "mirror.example.com/library/nginx:1.25@sha256:abc"},
+		{image: "tsuru/nginx:1.25", expected: "mirror.example.com/tsuru/nginx:1.25"},
+		{image: "docker.io/tsuru/nginx", expected: "mirror.example.com/tsuru/nginx"},
+		{image: "localhost:5000/nginx", expected: "mirror.example.com/nginx"},
+		{image: "mirror.example.com/library/nginx", expected: "mirror.example.com/library/nginx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			assert.Equal(t, tt.expected, mirrorImage(tt.image, "mirror.example.com/"))
+		})
+	}
+}
+
+func TestParseOperatorConfig(t *testing.T) {
+	config, err := ParseOperatorConfig([]byte(`
+image: nginx:1.25
+registryMirror: mirror.example.com
+watchNamespaces: [team-a, team-b]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, &OperatorConfig{
+		Image:           "nginx:1.25",
+		RegistryMirror:  "mirror.example.com",
+		WatchNamespaces: []string{"team-a", "team-b"},
+	}, config)
+
+	_, err = ParseOperatorConfig([]byte(`defaultImage: nginx:1.25`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown field "defaultImage"`)
+}
+
+func TestNginxReconciler_Reconcile_operatorConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("image: nginx:1.25\nregistryMirror: mirror.example.com\n"), 0600))
+
+	loader, err := NewOperatorConfigLoader(path, logr.Discard())
+	require.NoError(t, err)
+
+	nginx := &v1alpha1.Nginx{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(newScheme()).
+		WithRuntimeObjects(nginx).
+		Build()
+
+	r := &NginxReconciler{
+		Client:           client,
+		EventRecorder:    record.NewFakeRecorder(100),
+		Log:              logr.Discard(),
+		AnnotationFilter: labels.Everything(),
+		ConfigLoader:     loader,
+	}
+
+	_, err = r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "my-nginx", Namespace: "default"}})
+	require.NoError(t, err)
+
+	var dep appsv1.Deployment
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep))
+	assert.Equal(t, "mirror.example.com/library/nginx:1.25", dep.Spec.Template.Spec.Containers[0].Image)
+
+	var got v1alpha1.Nginx
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &got))
+	assert.Empty(t, got.Spec.Image, "the defaults should not be stored on the Nginx")
+
+	reqs := r.allNginxes(&v1alpha1.Nginx{})
+	assert.Equal(t, []ctrl.Request{{NamespacedName: types.NamespacedName{Name: "my-nginx", Namespace: "default"}}}, reqs)
+}
+
+func TestNginxReconciler_Reconcile_operatorConfigImageAfterWebhookDefaults(t *testing.T) {
+	tests := map[string]struct {
+		config        string
+		image         string
+		expectedImage string
+	}{
+		"without image in the config, should use the default image": {
+			config:        "registryMirror: \"\"\n",
+			expectedImage: "nginx:latest",
+		},
+
+		"with image in the config, should use the config one": {
+			config:        "image: nginx:1.25\n",
+			expectedImage: "nginx:1.25",
+		},
+
+		"with image on the Nginx, should use the Nginx one": {
+			config:        "image: nginx:1.25\n",
+			image:         "nginx:1.26",
+			expectedImage: "nginx:1.26",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.config), 0600))
+
+			loader, err := NewOperatorConfigLoader(path, logr.Discard())
+			require.NoError(t, err)
+
+			nginx := &v1alpha1.Nginx{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-nginx", Namespace: "default"},
+				Spec:       v1alpha1.NginxSpec{Image: tt.image},
+			}
+
+			// NOTE: as done by the mutating webhook on creation.
+			nginx.Default()
+
+			client := fake.NewClientBuilder().
+				WithScheme(newScheme()).
+				WithRuntimeObjects(nginx).
+				Build()
+
+			r := &NginxReconciler{
+				Client:           client,
+				EventRecorder:    record.NewFakeRecorder(100),
+				Log:              logr.Discard(),
+				AnnotationFilter: labels.Everything(),
+				ConfigLoader:     loader,
+			}
+
+			_, err = r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "my-nginx", Namespace: "default"}})
+			require.NoError(t, err)
+
+			var dep appsv1.Deployment
+			require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "my-nginx", Namespace: "default"}, &dep))
+			assert.Equal(t, tt.expectedImage, dep.Spec.Template.Spec.Containers[0].Image)
+		})
+	}
+}
+
+func TestOperatorConfigLoader_Start(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("image: nginx:1.25\n"), 0600))
+
+	loader, err := NewOperatorConfigLoader(path, logr.Discard())
+	require.NoError(t, err)
+	assert.Equal(t, "nginx:1.25", loader.Config().Image)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	go loader.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		assert.NoError(t, os.WriteFile(path, []byte("image: nginx:1.26\n"), 0600))
+		select {
+		case <-loader.changes:
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "nginx:1.26", loader.Config().Image)
+
+	require.NoError(t, os.WriteFile(path, []byte("image: [\n"), 0600))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, "nginx:1.26", loader.Config().Image, "an invalid config should not replace the previous one")
+}
@@ -5,22 +5,38 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	ctrlzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	nginxv1alpha1 "github.com/tsuru/nginx-operator/api/v1alpha1"
 	"github.com/tsuru/nginx-operator/controllers"
+	"github.com/tsuru/nginx-operator/pkg/certs"
+	"github.com/tsuru/nginx-operator/pkg/k8s"
 	"github.com/tsuru/nginx-operator/version"
 
 	// +kubebuilder:scaffold:imports
@@ -41,14 +57,39 @@ var (
 	leaderElection                  = flag.Bool("leader-elect", true, "Start a leader election client and gain leadership before executing the main loop. Enable this when running replicated components for high availability.")
 	leaderElectionResourceName      = flag.String("leader-elect-resource-name", "nginx-operator-lock", "The name of resource object that is used for locking during leader election.")
 	leaderElectionResourceNamespace = flag.String("leader-elect-resource-namespace", "", "The namespace of resource object that is used for locking during leader election.")
+	leaderElectionLeaseDuration     = flag.Duration("leader-elect-lease-duration", 15*time.Second, "The duration that non-leader candidates will wait after observing a leadership renewal until attempting to acquire leadership.")
+	leaderElectionRenewDeadline     = flag.Duration("leader-elect-renew-deadline", 10*time.Second, "The interval between attempts by the acting leader to renew a leadership slot before it stops leading. It must be less than the lease duration.")
+	leaderElectionRetryPeriod       = flag.Duration("leader-elect-retry-period", 2*time.Second, "The duration the clients should wait between attempting acquisition and renewal of a leadership.")
 
 	syncPeriod = flag.Duration("sync-period", 10*time.Hour, "The resync period for reconciling manager resources.")
+	workers    = flag.Int("workers", 1, "The maximum number of Nginx resources reconciled concurrently.")
+	dryRun     = flag.Bool("dry-run", false, "Only log (and emit as events) the changes the operator would make on the Nginx resources, without applying them.")
+
+	retryBaseDelay = flag.Duration("reconcile-retry-base-delay", time.Second, "The delay before retrying a failed reconcile of an Nginx resource. It doubles on every consecutive failure of the same resource.")
+	retryMaxDelay  = flag.Duration("reconcile-retry-max-delay", 5*time.Minute, "The maximum delay between retries of a failed reconcile of an Nginx resource.")
 
 	logFormat = flag.String("log-format", "json", "Set the format of logging (options: json, console)")
 	logLevel  = zap.LevelFlag("log-level", zapcore.InfoLevel, "Set the level of logging (options: debug, info, warn, error, dpanic, panic, fatal)")
 
-	namespace        = flag.String("namespace", "", "Limit the observed Nginx resources from specific namespace (empty means all namespaces)")
+	logLevelAddr = flag.String("log-level-bind-address", "127.0.0.1:8082", "The TCP address that controller should bind to for serving the unauthenticated /log-level endpoint, which changes the level of logging at runtime. Keep it on the loopback interface. It can be set to \"0\" to disable it.")
+
+	configFile = flag.String("config", "", "The operator config file (e.g. a mounted ConfigMap) with the defaults of the Nginx resources: image, resources, healthcheck, registryMirror and watchNamespaces. It's reloaded on changes.")
+
+	namespace        = flag.String("namespace", os.Getenv("WATCH_NAMESPACES"), "Limit the observed Nginx resources from specific namespaces, as a comma-separated list (empty means all namespaces). Defaults to the WATCH_NAMESPACES environment variable.")
 	annotationFilter = flag.String("annotation-filter", "", "Filter Nginx resources via annotation using label selector semantics (default: all Nginx resources)")
+
+	enableGatewayAPI = flag.Bool("enable-gateway-api", false, "Manage Gateway API HTTPRoutes for Nginx resources. Requires the Gateway API CRDs installed in the cluster.")
+
+	enableWebhooks = flag.Bool("enable-webhooks", false, "Serve the Nginx admission webhooks. Requires the TLS certificates in the webhook server's certificate directory, unless --webhook-cert-bootstrap is set.")
+	webhookPort    = flag.Int("webhook-bind-port", 9443, "The TCP port that the webhook server should bind to.")
+	webhookCertDir = flag.String("webhook-cert-dir", filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs"), "The directory that contains the webhook server key and certificate (tls.key and tls.crt).")
+
+	webhookCertBootstrap           = flag.Bool("webhook-cert-bootstrap", false, "Generate (and rotate) a self-signed certificate for the webhook server, injecting its CA into the webhook configurations. Useful when cert-manager is not available.")
+	webhookNamespace               = flag.String("webhook-namespace", os.Getenv("POD_NAMESPACE"), "The namespace of the webhook Service and certificate Secret. Defaults to the POD_NAMESPACE environment variable.")
+	webhookServiceName             = flag.String("webhook-service-name", "nginx-operator-webhook-service", "The name of the Service in front of the webhook server.")
+	webhookCertSecretName          = flag.String("webhook-cert-secret-name", "nginx-operator-webhook-server-cert", "The name of the Secret where the generated webhook certificates are stored.")
+	mutatingWebhookConfiguration   = flag.String("mutating-webhook-configuration-name", "nginx-operator-mutating-webhook-configuration", "The name of the MutatingWebhookConfiguration to inject the generated CA bundle into.")
+	validatingWebhookConfiguration = flag.String("validating-webhook-configuration-name", "nginx-operator-validating-webhook-configuration", "The name of the ValidatingWebhookConfiguration to inject the generated CA bundle into.")
 )
 
 func init() {
@@ -66,27 +107,85 @@ func main() {
 		logEncoder = zapcore.NewConsoleEncoder(zap.NewProductionEncoderConfig())
 	}
 
+	// NOTE: the level can be changed at runtime through the /log-level
+	// endpoint, served only on the loopback interface as it's not
+	// authenticated, e.g.
+	// kubectl port-forward deploy/nginx-operator-controller 8082 &
+	// curl -X PUT -d '{"level":"debug"}' localhost:8082/log-level
+	atomicLevel := zap.NewAtomicLevelAt(*logLevel)
+
 	ctrl.SetLogger(ctrlzap.New(
-		ctrlzap.Level(zap.NewAtomicLevelAt(*logLevel)),
+		ctrlzap.Level(atomicLevel),
 		ctrlzap.Encoder(logEncoder),
 	))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	options := ctrl.Options{
 		Scheme:                     scheme,
 		MetricsBindAddress:         *metricsAddr,
-		Namespace:                  *namespace,
 		LeaderElectionResourceLock: "leases",
 		LeaderElection:             *leaderElection,
 		LeaderElectionID:           *leaderElectionResourceName,
 		LeaderElectionNamespace:    *leaderElectionResourceNamespace,
+		LeaseDuration:              leaderElectionLeaseDuration,
+		RenewDeadline:              leaderElectionRenewDeadline,
+		RetryPeriod:                leaderElectionRetryPeriod,
 		SyncPeriod:                 syncPeriod,
 		HealthProbeBindAddress:     *healthAddr,
-	})
+		Port:                       *webhookPort,
+		CertDir:                    *webhookCertDir,
+	}
+
+	var configLoader *controllers.OperatorConfigLoader
+	if *configFile != "" {
+		var err error
+		configLoader, err = controllers.NewOperatorConfigLoader(*configFile, ctrl.Log.WithName("config"))
+		if err != nil {
+			ctrl.Log.Error(err, "unable to load operator config")
+			os.Exit(1)
+		}
+	}
+
+	var namespaces []string
+	for _, ns := range strings.Split(*namespace, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	if c := configLoader.Config(); len(namespaces) == 0 && c != nil {
+		namespaces = c.WatchNamespaces
+	}
+
+	switch {
+	case len(namespaces) == 1:
+		options.Namespace = namespaces[0]
+	case len(namespaces) > 1:
+		options.NewCache = cache.MultiNamespacedCacheBuilder(namespaces)
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
 		ctrl.Log.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	if *logLevelAddr != "0" {
+		mux := http.NewServeMux()
+		mux.Handle("/log-level", atomicLevel)
+
+		if err = mgr.Add(&httpServer{addr: *logLevelAddr, handler: mux}); err != nil {
+			ctrl.Log.Error(err, "unable to set up log level handler")
+			os.Exit(1)
+		}
+	}
+
+	if configLoader != nil {
+		if err = mgr.Add(configLoader); err != nil {
+			ctrl.Log.Error(err, "unable to set up operator config reloading")
+			os.Exit(1)
+		}
+	}
+
 	ls, err := metav1.ParseToLabelSelector(*annotationFilter)
 	if err != nil {
 		ctrl.Log.Error(err, "unable to convert annotation filter to label selector")
@@ -99,17 +198,81 @@ func main() {
 		os.Exit(1)
 	}
 
+	enableServiceMonitor, err := isKindAvailable(mgr, k8s.ServiceMonitorGroupVersionKind)
+	if err != nil {
+		ctrl.Log.Error(err, "unable to check whether ServiceMonitor kind is available")
+		os.Exit(1)
+	}
+
+	if !enableServiceMonitor {
+		ctrl.Log.Info("ServiceMonitor kind not found in the cluster, skipping its management")
+	}
+
 	err = (&controllers.NginxReconciler{
-		Client:           mgr.GetClient(),
-		EventRecorder:    mgr.GetEventRecorderFor("nginx-operator"),
-		Log:              ctrl.Log.WithName("controllers").WithName("Nginx"),
-		Scheme:           mgr.GetScheme(),
-		AnnotationFilter: annotationSelector,
+		Client:                  mgr.GetClient(),
+		APIReader:               mgr.GetAPIReader(),
+		EventRecorder:           mgr.GetEventRecorderFor("nginx-operator"),
+		Log:                     ctrl.Log.WithName("controllers").WithName("Nginx"),
+		Scheme:                  mgr.GetScheme(),
+		AnnotationFilter:        annotationSelector,
+		EnableGatewayAPI:        *enableGatewayAPI,
+		EnableServiceMonitor:    enableServiceMonitor,
+		MaxConcurrentReconciles: *workers,
+		RetryBaseDelay:          *retryBaseDelay,
+		RetryMaxDelay:           *retryMaxDelay,
+		DryRun:                  *dryRun,
+		ConfigLoader:            configLoader,
 	}).SetupWithManager(mgr)
 	if err != nil {
 		ctrl.Log.Error(err, "unable to create controller", "controller", "Nginx")
 		os.Exit(1)
 	}
+
+	metrics.Registry.MustRegister(&controllers.NginxCollector{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("metrics").WithName("Nginx"),
+	})
+
+	ctx := ctrl.SetupSignalHandler()
+
+	if *enableWebhooks && *webhookCertBootstrap {
+		// NOTE: the certificates must be in place before the webhook server
+		// starts, so they're ensured using a client not backed by the
+		// manager's cache (which is only started along with the manager).
+		c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			ctrl.Log.Error(err, "unable to create client for the webhook certificates")
+			os.Exit(1)
+		}
+
+		bootstrapper := &certs.Bootstrapper{
+			Client:                         c,
+			Log:                            ctrl.Log.WithName("certs"),
+			CertDir:                        *webhookCertDir,
+			Namespace:                      *webhookNamespace,
+			ServiceName:                    *webhookServiceName,
+			SecretName:                     *webhookCertSecretName,
+			MutatingWebhookConfiguration:   *mutatingWebhookConfiguration,
+			ValidatingWebhookConfiguration: *validatingWebhookConfiguration,
+		}
+
+		if err = bootstrapper.Ensure(ctx); err != nil {
+			ctrl.Log.Error(err, "unable to bootstrap the webhook certificates")
+			os.Exit(1)
+		}
+
+		if err = mgr.Add(bootstrapper); err != nil {
+			ctrl.Log.Error(err, "unable to set up the webhook certificates rotation")
+			os.Exit(1)
+		}
+	}
+
+	if *enableWebhooks {
+		if err = (&nginxv1alpha1.Nginx{}).SetupWebhookWithManager(mgr); err != nil {
+			ctrl.Log.Error(err, "unable to create webhook", "webhook", "Nginx")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -117,15 +280,91 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	readyz, err := readyzCheck(mgr)
+	if err != nil {
+		ctrl.Log.Error(err, "unable to create ready check")
+		os.Exit(1)
+	}
+
+	if err := mgr.AddReadyzCheck("readyz", readyz); err != nil {
 		ctrl.Log.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
 
 	ctrl.Log.Info("starting manager", "version", version.Version, "commit", version.GitCommit)
 
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		ctrl.Log.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// httpServer serves the handler on the address until the manager stops, on
+// every replica.
+type httpServer struct {
+	addr    string
+	handler http.Handler
+}
+
+func (s *httpServer) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Handler: s.handler, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	if err = srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *httpServer) NeedLeaderElection() bool {
+	return false
+}
+
+// readyzCheck returns a check which reports the operator as ready once the
+// informer caches are synced and the API server is reachable.
+func readyzCheck(mgr ctrl.Manager) (healthz.Checker, error) {
+	const timeout = 2 * time.Second
+
+	config := rest.CopyConfig(mgr.GetConfig())
+	config.Timeout = timeout
+
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+
+		if !mgr.GetCache().WaitForCacheSync(ctx) {
+			return errors.New("informer caches not synced yet")
+		}
+
+		if _, err := dc.ServerVersion(); err != nil {
+			return fmt.Errorf("unable to reach the API server: %w", err)
+		}
+
+		return nil
+	}, nil
+}
+
+// isKindAvailable returns whether the API server serves the given kind, e.g.
+// whether the CRD which defines it is installed.
+func isKindAvailable(mgr ctrl.Manager, gvk schema.GroupVersionKind) (bool, error) {
+	_, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
@@ -0,0 +1,402 @@
+// Copyright 2026 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package certs provisions a self-signed serving certificate for the admission
+// webhooks, so they can be enabled on clusters without cert-manager.
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CACertKey is the key of the Secret holding the PEM-encoded CA bundle
+	// the API server should trust to call the webhooks.
+	CACertKey = "ca.crt"
+
+	defaultValidity      = 365 * 24 * time.Hour
+	defaultRotateBefore  = 30 * 24 * time.Hour
+	defaultCheckInterval = time.Hour
+)
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations;validatingwebhookconfigurations,verbs=get;update
+
+// Bootstrapper keeps a self-signed serving certificate for the webhook server.
+//
+// The CA and serving certificate are stored in a Secret, shared by every
+// replica of the operator, and rotated some time before they expire. Each
+// replica writes the certificate to the webhook server's certificate
+// directory (which reloads it on changes) and injects the CA bundle into the
+// webhook configurations.
+type Bootstrapper struct {
+	// Client must not be backed by a cache, as the bootstrap runs before the
+	// manager (and its caches) starts.
+	Client client.Client
+	Log    logr.Logger
+
+	// CertDir is the directory the webhook server loads tls.crt and tls.key
+	// from.
+	CertDir string
+
+	// Namespace and ServiceName identify the Service in front of the webhook
+	// server, the certificate is issued for its DNS names.
+	Namespace   string
+	ServiceName string
+
+	// SecretName is the name of the Secret, in Namespace, where the
+	// certificates are stored.
+	SecretName string
+
+	// MutatingWebhookConfiguration and ValidatingWebhookConfiguration are the
+	// names of the webhook configurations to inject the CA bundle into. Empty
+	// means none.
+	MutatingWebhookConfiguration   string
+	ValidatingWebhookConfiguration string
+
+	// Validity is how long the generated certificates are valid for. Defaults
+	// to one year.
+	Validity time.Duration
+
+	// RotateBefore is how long before their expiration the certificates are
+	// rotated. Defaults to 30 days.
+	RotateBefore time.Duration
+
+	// CheckInterval is how often the certificates are checked for rotation.
+	// Defaults to one hour.
+	CheckInterval time.Duration
+}
+
+// Start periodically ensures the certificates, until the context is done. It
+// implements the manager.Runnable interface.
+func (b *Bootstrapper) Start(ctx context.Context) error {
+	interval := b.CheckInterval
+	if interval == 0 {
+		interval = defaultCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			if err := b.Ensure(ctx); err != nil {
+				b.Log.Error(err, "Unable to ensure the webhook certificates")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface,
+// every replica serves the webhooks so it must keep its certificate.
+func (b *Bootstrapper) NeedLeaderElection() bool {
+	return false
+}
+
+// Ensure generates (or rotates) the certificates when needed, writes them to
+// the certificate directory and injects the CA bundle into the webhook
+// configurations.
+func (b *Bootstrapper) Ensure(ctx context.Context) error {
+	var secret corev1.Secret
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return b.ensureSecret(ctx, &secret)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ensure the certificates Secret: %w", err)
+	}
+
+	if err = b.writeCerts(secret.Data); err != nil {
+		return fmt.Errorf("failed to write the certificates: %w", err)
+	}
+
+	if err = b.injectCABundle(ctx, secret.Data[CACertKey]); err != nil {
+		return fmt.Errorf("failed to inject the CA bundle: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Bootstrapper) ensureSecret(ctx context.Context, secret *corev1.Secret) error {
+	err := b.Client.Get(ctx, client.ObjectKey{Name: b.SecretName, Namespace: b.Namespace}, secret)
+	if k8serrors.IsNotFound(err) {
+		data, err := b.generate(nil)
+		if err != nil {
+			return err
+		}
+
+		*secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: b.SecretName, Namespace: b.Namespace},
+			Type:       corev1.SecretTypeTLS,
+			Data:       data,
+		}
+
+		err = b.Client.Create(ctx, secret)
+		if k8serrors.IsAlreadyExists(err) {
+			// Another replica has just created it, let's retry using theirs.
+			return k8serrors.NewConflict(corev1.Resource("secrets"), b.SecretName, err)
+		}
+
+		if err == nil {
+			b.Log.Info("Webhook certificates generated", "secret", b.SecretName, "namespace", b.Namespace)
+		}
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if b.valid(secret.Data) {
+		return nil
+	}
+
+	data, err := b.generate(secret.Data[CACertKey])
+	if err != nil {
+		return err
+	}
+
+	secret.Type = corev1.SecretTypeTLS
+	secret.Data = data
+
+	if err = b.Client.Update(ctx, secret); err != nil {
+		return err
+	}
+
+	b.Log.Info("Webhook certificates rotated", "secret", b.SecretName, "namespace", b.Namespace)
+	return nil
+}
+
+// valid returns whether the stored certificate was issued for the webhook
+// Service and is far from expiring.
+func (b *Bootstrapper) valid(data map[string][]byte) bool {
+	if len(data[CACertKey]) == 0 || len(data[corev1.TLSPrivateKeyKey]) == 0 {
+		return false
+	}
+
+	block, _ := pem.Decode(data[corev1.TLSCertKey])
+	if block == nil {
+		return false
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	if time.Now().Add(b.rotateBefore()).After(cert.NotAfter) {
+		return false
+	}
+
+	return cert.VerifyHostname(b.dnsNames()[0]) == nil
+}
+
+// generate returns a new CA and a serving certificate signed by it. The
+// previous CA, if given, is kept in the CA bundle so the API server still
+// trusts the replicas which have not reloaded the new certificate yet.
+func (b *Bootstrapper) generate(previousCA []byte) (map[string][]byte, error) {
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := time.Now().Add(b.validity())
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          serialNumber(),
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("%s.%s CA", b.ServiceName, b.Namespace)},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	dnsNames := b.dnsNames()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber(),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	if block, _ := pem.Decode(previousCA); block != nil {
+		caBundle = append(caBundle, pem.EncodeToMemory(block)...)
+	}
+
+	return map[string][]byte{
+		CACertKey:               caBundle,
+		corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// writeCerts writes the serving certificate and key in the certificate
+// directory, unless they're already up to date.
+func (b *Bootstrapper) writeCerts(data map[string][]byte) error {
+	if err := os.MkdirAll(b.CertDir, 0700); err != nil {
+		return err
+	}
+
+	// NOTE: the key is written first as the webhook server reloads the
+	// certificate on every write, so the last reload sees a matching pair.
+	for _, name := range []string{corev1.TLSPrivateKeyKey, corev1.TLSCertKey} {
+		path := filepath.Join(b.CertDir, name)
+
+		current, err := os.ReadFile(path)
+		if err == nil && bytes.Equal(current, data[name]) {
+			continue
+		}
+
+		if err = os.WriteFile(path, data[name], 0600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *Bootstrapper) injectCABundle(ctx context.Context, caBundle []byte) error {
+	if b.MutatingWebhookConfiguration != "" {
+		var config admissionregistrationv1.MutatingWebhookConfiguration
+		err := b.injectInto(ctx, b.MutatingWebhookConfiguration, &config, caBundle, func() []*admissionregistrationv1.WebhookClientConfig {
+			var configs []*admissionregistrationv1.WebhookClientConfig
+			for i := range config.Webhooks {
+				configs = append(configs, &config.Webhooks[i].ClientConfig)
+			}
+			return configs
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.ValidatingWebhookConfiguration != "" {
+		var config admissionregistrationv1.ValidatingWebhookConfiguration
+		err := b.injectInto(ctx, b.ValidatingWebhookConfiguration, &config, caBundle, func() []*admissionregistrationv1.WebhookClientConfig {
+			var configs []*admissionregistrationv1.WebhookClientConfig
+			for i := range config.Webhooks {
+				configs = append(configs, &config.Webhooks[i].ClientConfig)
+			}
+			return configs
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// injectInto sets the CA bundle on the client configs (as returned by
+// clientConfigs after obj is fetched) of the named webhook configuration.
+func (b *Bootstrapper) injectInto(ctx context.Context, name string, obj client.Object, caBundle []byte, clientConfigs func() []*admissionregistrationv1.WebhookClientConfig) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := b.Client.Get(ctx, client.ObjectKey{Name: name}, obj); err != nil {
+			return err
+		}
+
+		changed := false
+		for _, cc := range clientConfigs() {
+			if !bytes.Equal(cc.CABundle, caBundle) {
+				cc.CABundle = caBundle
+				changed = true
+			}
+		}
+
+		if !changed {
+			return nil
+		}
+
+		return b.Client.Update(ctx, obj)
+	})
+}
+
+func (b *Bootstrapper) dnsNames() []string {
+	return []string{
+		fmt.Sprintf("%s.%s.svc", b.ServiceName, b.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", b.ServiceName, b.Namespace),
+		fmt.Sprintf("%s.%s", b.ServiceName, b.Namespace),
+		b.ServiceName,
+	}
+}
+
+func (b *Bootstrapper) validity() time.Duration {
+	if b.Validity == 0 {
+		return defaultValidity
+	}
+
+	return b.Validity
+}
+
+func (b *Bootstrapper) rotateBefore() time.Duration {
+	if b.RotateBefore == 0 {
+		return defaultRotateBefore
+	}
+
+	return b.RotateBefore
+}
+
+func serialNumber() *big.Int {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+
+	return n
+}
@@ -0,0 +1,132 @@
+// Copyright 2026 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newBootstrapper(t *testing.T, c client.Client) *Bootstrapper {
+	return &Bootstrapper{
+		Client:                         c,
+		Log:                            logr.Discard(),
+		CertDir:                        t.TempDir(),
+		Namespace:                      "nginx-operator-system",
+		ServiceName:                    "nginx-operator-webhook-service",
+		SecretName:                     "nginx-operator-webhook-server-cert",
+		MutatingWebhookConfiguration:   "nginx-operator-mutating-webhook-configuration",
+		ValidatingWebhookConfiguration: "nginx-operator-validating-webhook-configuration",
+	}
+}
+
+func newClient() client.Client {
+	return fake.NewClientBuilder().
+		WithScheme(clientgoscheme.Scheme).
+		WithRuntimeObjects(
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "nginx-operator-mutating-webhook-configuration"},
+				Webhooks:   []admissionregistrationv1.MutatingWebhook{{Name: "mnginx.kb.io"}},
+			},
+			&admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "nginx-operator-validating-webhook-configuration"},
+				Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "vnginx.kb.io"}},
+			},
+		).
+		Build()
+}
+
+func getSecret(t *testing.T, c client.Client) corev1.Secret {
+	var secret corev1.Secret
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Name: "nginx-operator-webhook-server-cert", Namespace: "nginx-operator-system"}, &secret))
+	return secret
+}
+
+func TestBootstrapper_Ensure(t *testing.T) {
+	c := newClient()
+	b := newBootstrapper(t, c)
+
+	require.NoError(t, b.Ensure(context.TODO()))
+
+	secret := getSecret(t, c)
+	assert.Equal(t, corev1.SecretTypeTLS, secret.Type)
+
+	pair, err := tls.LoadX509KeyPair(filepath.Join(b.CertDir, "tls.crt"), filepath.Join(b.CertDir, "tls.key"))
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(secret.Data["ca.crt"]))
+
+	_, err = cert.Verify(x509.VerifyOptions{
+		DNSName: "nginx-operator-webhook-service.nginx-operator-system.svc",
+		Roots:   roots,
+	})
+	assert.NoError(t, err)
+
+	var mutating admissionregistrationv1.MutatingWebhookConfiguration
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Name: "nginx-operator-mutating-webhook-configuration"}, &mutating))
+	assert.Equal(t, secret.Data["ca.crt"], mutating.Webhooks[0].ClientConfig.CABundle)
+
+	var validating admissionregistrationv1.ValidatingWebhookConfiguration
+	require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Name: "nginx-operator-validating-webhook-configuration"}, &validating))
+	assert.Equal(t, secret.Data["ca.crt"], validating.Webhooks[0].ClientConfig.CABundle)
+
+	t.Run("reuses the stored certificates", func(t *testing.T) {
+		other := newBootstrapper(t, c)
+		require.NoError(t, other.Ensure(context.TODO()))
+
+		assert.Equal(t, secret.Data, getSecret(t, c).Data)
+
+		data, err := os.ReadFile(filepath.Join(other.CertDir, "tls.crt"))
+		require.NoError(t, err)
+		assert.Equal(t, secret.Data["tls.crt"], data)
+	})
+
+	t.Run("rotates the certificates about to expire", func(t *testing.T) {
+		b.RotateBefore = 2 * 365 * 24 * time.Hour
+		require.NoError(t, b.Ensure(context.TODO()))
+
+		rotated := getSecret(t, c)
+		assert.NotEqual(t, secret.Data["tls.crt"], rotated.Data["tls.crt"])
+		assert.Contains(t, string(rotated.Data["ca.crt"]), string(secret.Data["ca.crt"]), "the previous CA should be kept in the bundle")
+
+		data, err := os.ReadFile(filepath.Join(b.CertDir, "tls.crt"))
+		require.NoError(t, err)
+		assert.Equal(t, rotated.Data["tls.crt"], data)
+
+		require.NoError(t, c.Get(context.TODO(), client.ObjectKey{Name: "nginx-operator-mutating-webhook-configuration"}, &mutating))
+		assert.Equal(t, rotated.Data["ca.crt"], mutating.Webhooks[0].ClientConfig.CABundle)
+	})
+}
+
+func TestBootstrapper_Ensure_anotherService(t *testing.T) {
+	c := newClient()
+	require.NoError(t, newBootstrapper(t, c).Ensure(context.TODO()))
+	secret := getSecret(t, c)
+
+	b := newBootstrapper(t, c)
+	b.ServiceName = "another-webhook-service"
+	require.NoError(t, b.Ensure(context.TODO()))
+
+	assert.NotEqual(t, secret.Data["tls.crt"], getSecret(t, c).Data["tls.crt"])
+}
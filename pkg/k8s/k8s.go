@@ -11,12 +11,17 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"text/template"
 
 	appv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	k8slabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -26,20 +31,19 @@ import (
 )
 
 const (
-	// Default docker image used for nginx
-	defaultNginxImage = "nginx:latest"
-
 	// Default port names used by the nginx container and the ClusterIP service
-	defaultHTTPPort            = int32(8080)
-	defaultHTTPHostNetworkPort = int32(80)
-	defaultHTTPPortName        = "http"
+	defaultHTTPPort            = v1alpha1.DefaultHTTPPort
+	defaultHTTPHostNetworkPort = v1alpha1.DefaultHTTPHostNetworkPort
+	defaultHTTPPortName        = v1alpha1.DefaultHTTPPortName
 
-	defaultHTTPSPort            = int32(8443)
-	defaultHTTPSHostNetworkPort = int32(443)
-	defaultHTTPSPortName        = "https"
+	defaultHTTPSPort            = v1alpha1.DefaultHTTPSPort
+	defaultHTTPSHostNetworkPort = v1alpha1.DefaultHTTPSHostNetworkPort
+	defaultHTTPSPortName        = v1alpha1.DefaultHTTPSPortName
 
 	defaultCacheVolumeExtraSize = float64(1.05)
 
+	defaultTargetCPUUtilizationPercentage = int32(80)
+
 	curlProbeCommand = "curl -m%d -kfsS -o /dev/null %s"
 
 	// Mount path where nginx.conf will be placed
@@ -51,19 +55,75 @@ const (
 	// Mount path where certificate and key pair will be placed
 	certMountPath = configMountPath + "/certs"
 
+	// Mount path where the client certificate CA bundles will be placed
+	clientCAMountPath = configMountPath + "/client-ca"
+
 	// Mount path where the additional files will be mounted on
 	extraFilesMountPath = configMountPath + "/extra_files"
 
+	defaultConfigFilesMountPath = configMountPath + "/conf.d"
+
 	// Annotation key used to stored the nginx that created the deployment
 	generatedFromAnnotation = "nginx.tsuru.io/generated-from"
+
+	// CustomNginxConfigAnnotation is the pod annotation key which holds the
+	// inline nginx configuration.
+	CustomNginxConfigAnnotation = "nginx.tsuru.io/custom-nginx-config"
+
+	metricsExporterContainerName = v1alpha1.MetricsExporterContainerName
+	logShipperContainerName      = v1alpha1.LogShipperContainerName
+
+	defaultLogShipperImage      = "fluent/fluent-bit:1.9"
+	defaultLogShipperConfigPath = "/fluent-bit/etc"
+	defaultLogsPath             = "/var/log/nginx"
+
+	defaultMetricsExporterImage = "nginx/nginx-prometheus-exporter:0.11.0"
+	defaultMetricsPort          = int32(9113)
+	defaultMetricsPortName      = "metrics"
+	defaultStubStatusPath       = "/stub_status"
+
+	// Max duration allowed for the configuration validation Job to finish
+	configValidationDeadlineSeconds = int64(300)
 )
 
+// HTTPRouteGroupVersionKind is the kind of the Gateway API route created for
+// the Nginx resources.
+var HTTPRouteGroupVersionKind = schema.GroupVersionKind{
+	Group:   "gateway.networking.k8s.io",
+	Version: "v1beta1",
+	Kind:    "HTTPRoute",
+}
+
+// ServiceMonitorGroupVersionKind is the kind of the Prometheus Operator
+// resource created to scrape the Nginx metrics.
+var ServiceMonitorGroupVersionKind = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "ServiceMonitor",
+}
+
 var nginxEntrypoint = []string{
 	"/bin/sh",
 	"-c",
 	"while ! [ -f /tmp/done ]; do [ -f /tmp/error ] && cat /tmp/error >&2; sleep 0.5; done && exec nginx -g 'daemon off;'",
 }
 
+// nginxCustomEntrypoint waits the postStart hook like nginxEntrypoint, then
+// executes the command given as the container args.
+var nginxCustomEntrypoint = []string{
+	"/bin/sh",
+	"-c",
+	`while ! [ -f /tmp/done ]; do [ -f /tmp/error ] && cat /tmp/error >&2; sleep 0.5; done && exec "$@"`,
+	"sh",
+}
+
+var defaultNginxCommand = []string{"nginx"}
+
+// NOTE: nginx is the main process of the container (see nginxEntrypoint), so
+// the hook keeps running until it exits, otherwise the kubelet would send it a
+// SIGTERM (fast shutdown) as soon as the hook returns.
+const gracefulShutdownCommand = "sleep %d && nginx -s quit && while [ -d /proc/1 ]; do sleep 1; done"
+
 var defaultPostStartCommand = []string{
 	"/bin/sh",
 	"-c",
@@ -72,8 +132,7 @@ var defaultPostStartCommand = []string{
 
 // NewDeployment creates a deployment for a given Nginx resource.
 func NewDeployment(n *v1alpha1.Nginx) (*appv1.Deployment, error) {
-	n.Spec.Image = valueOrDefault(n.Spec.Image, defaultNginxImage)
-	setDefaultPorts(&n.Spec.PodTemplate)
+	n.Spec.SetDefaults()
 
 	containerSecurityContext := n.Spec.PodTemplate.ContainerSecurityContext
 
@@ -106,6 +165,34 @@ func NewDeployment(n *v1alpha1.Nginx) (*appv1.Deployment, error) {
 		maxSurge, maxUnavailable = ru.MaxSurge, ru.MaxUnavailable
 	}
 
+	strategy := appv1.DeploymentStrategy{
+		Type: appv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appv1.RollingUpdateDeployment{
+			MaxUnavailable: maxUnavailable,
+			MaxSurge:       maxSurge,
+		},
+	}
+
+	if s := n.Spec.Strategy; s != nil {
+		if s.Type == appv1.RecreateDeploymentStrategyType {
+			strategy = appv1.DeploymentStrategy{Type: appv1.RecreateDeploymentStrategyType}
+		} else if s.RollingUpdate != nil {
+			strategy.RollingUpdate = s.RollingUpdate.DeepCopy()
+		}
+	}
+
+	serviceAccountName := n.Spec.PodTemplate.ServiceAccountName
+	if n.Spec.ServiceAccount != nil {
+		serviceAccountName = n.Name
+	}
+
+	replicas := n.Spec.Replicas
+	if n.Spec.Autoscaling != nil {
+		// NOTE: leaving the replicas unset so the number of pods set by the
+		// HorizontalPodAutoscaler is preserved.
+		replicas = nil
+	}
+
 	deployment := appv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Deployment",
@@ -124,14 +211,8 @@ func NewDeployment(n *v1alpha1.Nginx) (*appv1.Deployment, error) {
 			Labels: LabelsForNginx(n.Name),
 		},
 		Spec: appv1.DeploymentSpec{
-			Strategy: appv1.DeploymentStrategy{
-				Type: appv1.RollingUpdateDeploymentStrategyType,
-				RollingUpdate: &appv1.RollingUpdateDeployment{
-					MaxUnavailable: maxUnavailable,
-					MaxSurge:       maxSurge,
-				},
-			},
-			Replicas: n.Spec.Replicas,
+			Strategy: strategy,
+			Replicas: replicas,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: LabelsForNginx(n.Name),
 			},
@@ -139,24 +220,27 @@ func NewDeployment(n *v1alpha1.Nginx) (*appv1.Deployment, error) {
 				ObjectMeta: metav1.ObjectMeta{
 					Namespace:   n.Namespace,
 					Annotations: n.Spec.PodTemplate.Annotations,
-					Labels:      mergeMap(LabelsForNginx(n.Name), n.Spec.PodTemplate.Labels),
+					Labels:      mergeMap(mergeMap(map[string]string{}, n.Spec.PodTemplate.Labels), LabelsForNginx(n.Name)),
 				},
 				Spec: corev1.PodSpec{
-					ServiceAccountName: n.Spec.PodTemplate.ServiceAccountName,
+					ServiceAccountName: serviceAccountName,
 					EnableServiceLinks: func(b bool) *bool { return &b }(false),
 					Containers: append([]corev1.Container{
 						{
 							Name:            "nginx",
 							Image:           n.Spec.Image,
+							ImagePullPolicy: n.Spec.ImagePullPolicy,
 							Command:         nginxEntrypoint,
 							Resources:       n.Spec.Resources,
 							SecurityContext: containerSecurityContext,
 							Ports:           n.Spec.PodTemplate.Ports,
+							Env:             n.Spec.PodTemplate.Env,
+							EnvFrom:         n.Spec.PodTemplate.EnvFrom,
 							VolumeMounts:    n.Spec.PodTemplate.VolumeMounts,
 						},
 					}, n.Spec.PodTemplate.Containers...),
 					InitContainers:                n.Spec.PodTemplate.InitContainers,
-					Affinity:                      n.Spec.PodTemplate.Affinity,
+					Affinity:                      affinity(n),
 					NodeSelector:                  n.Spec.PodTemplate.NodeSelector,
 					HostNetwork:                   n.Spec.PodTemplate.HostNetwork,
 					TerminationGracePeriodSeconds: n.Spec.PodTemplate.TerminationGracePeriodSeconds,
@@ -164,16 +248,42 @@ func NewDeployment(n *v1alpha1.Nginx) (*appv1.Deployment, error) {
 					Tolerations:                   n.Spec.PodTemplate.Toleration,
 					TopologySpreadConstraints:     n.Spec.PodTemplate.TopologySpreadConstraints,
 					SecurityContext:               n.Spec.PodTemplate.PodSecurityContext,
+					DNSPolicy:                     n.Spec.PodTemplate.DNSPolicy,
+					DNSConfig:                     n.Spec.PodTemplate.DNSConfig,
+					HostAliases:                   n.Spec.PodTemplate.HostAliases,
+					PriorityClassName:             n.Spec.PodTemplate.PriorityClassName,
+					ImagePullSecrets:              n.Spec.ImagePullSecrets,
+					RuntimeClassName:              n.Spec.PodTemplate.RuntimeClassName,
 				},
 			},
 		},
 	}
+	if n.Spec.PodTemplate.HostNetwork && n.Spec.PodTemplate.DNSPolicy == "" {
+		// NOTE: pods on the host network get the node's resolv.conf by
+		// default, losing the cluster DNS used to resolve upstream Services.
+		deployment.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+	}
+
+	setupCommand(n.Spec.PodTemplate, &deployment)
 	setupProbes(n.Spec, &deployment)
-	setupConfig(n.Spec.Config, &deployment)
+	conf := n.Spec.Config
+	if conf != nil && conf.Kind == v1alpha1.ConfigKindTemplate {
+		rendered, err := renderConfigTemplate(n)
+		if err != nil {
+			return nil, err
+		}
+
+		conf = &v1alpha1.ConfigRef{Kind: v1alpha1.ConfigKindInline, Value: rendered}
+	}
+
+	setupConfig(conf, &deployment)
+	setupConfigFiles(n.Spec.Config, &deployment)
 	setupTLS(n.Spec.TLS, &deployment)
 	setupExtraFiles(n.Spec.ExtraFiles, &deployment)
 	setupCacheVolume(n.Spec.Cache, &deployment)
 	setupLifecycle(n.Spec.Lifecycle, &deployment)
+	setupMetrics(n.Spec, &deployment)
+	setupLogShipper(n.Spec.Logging, &deployment)
 
 	// This is done on the last step because n.Spec may have mutated during these methods
 	if err := SetNginxSpec(&deployment.ObjectMeta, n.Spec); err != nil {
@@ -183,6 +293,59 @@ func NewDeployment(n *v1alpha1.Nginx) (*appv1.Deployment, error) {
 	return &deployment, nil
 }
 
+// NewDaemonSet creates a DaemonSet for a given Nginx resource, running the same
+// pod template built by NewDeployment.
+func NewDaemonSet(n *v1alpha1.Nginx) (*appv1.DaemonSet, error) {
+	deployment, err := NewDeployment(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return &appv1.DaemonSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "DaemonSet",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: deployment.ObjectMeta,
+		Spec: appv1.DaemonSetSpec{
+			Selector: deployment.Spec.Selector,
+			Template: deployment.Spec.Template,
+		},
+	}, nil
+}
+
+// affinity returns the pod affinity, including the default anti-affinity
+// (when enabled) which spreads the pods across nodes and zones.
+func affinity(n *v1alpha1.Nginx) *corev1.Affinity {
+	aa := n.Spec.PodTemplate.DefaultAntiAffinity
+	if aa == nil {
+		return n.Spec.PodTemplate.Affinity
+	}
+
+	affinity := &corev1.Affinity{}
+	if n.Spec.PodTemplate.Affinity != nil {
+		affinity = n.Spec.PodTemplate.Affinity.DeepCopy()
+	}
+
+	selector := &metav1.LabelSelector{MatchLabels: LabelsForNginx(n.Name)}
+	nodeTerm := corev1.PodAffinityTerm{LabelSelector: selector, TopologyKey: corev1.LabelHostname}
+	zoneTerm := corev1.PodAffinityTerm{LabelSelector: selector, TopologyKey: corev1.LabelTopologyZone}
+
+	affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	if aa.Required {
+		affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = []corev1.PodAffinityTerm{nodeTerm}
+	} else {
+		affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = []corev1.WeightedPodAffinityTerm{
+			{Weight: 100, PodAffinityTerm: nodeTerm},
+		}
+	}
+
+	affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+		corev1.WeightedPodAffinityTerm{Weight: 50, PodAffinityTerm: zoneTerm})
+
+	return affinity
+}
+
 func mergeMap(a, b map[string]string) map[string]string {
 	if a == nil {
 		return b
@@ -195,22 +358,60 @@ func mergeMap(a, b map[string]string) map[string]string {
 
 // NewService assembles the ClusterIP service for the Nginx
 func NewService(n *v1alpha1.Nginx) *corev1.Service {
+	service := newService(n, n.Name+"-service", n.Spec.Service)
+
+	if m := n.Spec.Metrics; m != nil && m.Enabled {
+		service.Spec.Ports = append(service.Spec.Ports, corev1.ServicePort{
+			Name:       defaultMetricsPortName,
+			Protocol:   corev1.ProtocolTCP,
+			TargetPort: intstr.FromString(defaultMetricsPortName),
+			Port:       metricsPort(m),
+		})
+	}
+
+	return service
+}
+
+// InternalServiceName returns the name of the internal Service of the Nginx
+// with the given name.
+func InternalServiceName(name string) string {
+	return name + "-internal"
+}
+
+// NewInternalService assembles the Service described by spec.internalService,
+// which is meant to expose the Nginx within the cluster (or a private
+// network) alongside the main Service.
+func NewInternalService(n *v1alpha1.Nginx) *corev1.Service {
+	return newService(n, InternalServiceName(n.Name), n.Spec.InternalService)
+}
+
+func newService(n *v1alpha1.Nginx, name string, s *v1alpha1.NginxService) *corev1.Service {
 	annotations := map[string]string{}
 	labels := map[string]string{}
 
 	var lbIP string
+	var lbSourceRanges []string
 	var externalTrafficPolicy corev1.ServiceExternalTrafficPolicyType
+	var sessionAffinity corev1.ServiceAffinity
+	var sessionAffinityConfig *corev1.SessionAffinityConfig
+	var ipFamilies []corev1.IPFamily
+	var ipFamilyPolicy *corev1.IPFamilyPolicyType
 	labelSelector := LabelsForNginx(n.Name)
 
-	if n.Spec.Service != nil {
-		labels = n.Spec.Service.Labels
+	if s != nil {
+		labels = s.Labels
 
-		if n.Spec.Service.Annotations != nil {
-			annotations = n.Spec.Service.Annotations
+		if s.Annotations != nil {
+			annotations = s.Annotations
 		}
-		lbIP = n.Spec.Service.LoadBalancerIP
-		externalTrafficPolicy = n.Spec.Service.ExternalTrafficPolicy
-		if n.Spec.Service.UsePodSelector != nil && !*n.Spec.Service.UsePodSelector {
+		lbIP = s.LoadBalancerIP
+		lbSourceRanges = s.LoadBalancerSourceRanges
+		externalTrafficPolicy = s.ExternalTrafficPolicy
+		sessionAffinity = s.SessionAffinity
+		sessionAffinityConfig = s.SessionAffinityConfig
+		ipFamilies = s.IPFamilies
+		ipFamilyPolicy = s.IPFamilyPolicy
+		if s.UsePodSelector != nil && !*s.UsePodSelector {
 			labelSelector = nil
 		}
 	}
@@ -220,7 +421,7 @@ func NewService(n *v1alpha1.Nginx) *corev1.Service {
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      n.Name + "-service",
+			Name:      name,
 			Namespace: n.Namespace,
 			OwnerReferences: []metav1.OwnerReference{
 				*metav1.NewControllerRef(n, schema.GroupVersionKind{
@@ -233,24 +434,16 @@ func NewService(n *v1alpha1.Nginx) *corev1.Service {
 			Annotations: annotations,
 		},
 		Spec: corev1.ServiceSpec{
-			Ports: []corev1.ServicePort{
-				{
-					Name:       defaultHTTPPortName,
-					Protocol:   corev1.ProtocolTCP,
-					TargetPort: intstr.FromString(defaultHTTPPortName),
-					Port:       int32(80),
-				},
-				{
-					Name:       defaultHTTPSPortName,
-					Protocol:   corev1.ProtocolTCP,
-					TargetPort: intstr.FromString(defaultHTTPSPortName),
-					Port:       int32(443),
-				},
-			},
-			Selector:              labelSelector,
-			LoadBalancerIP:        lbIP,
-			Type:                  nginxService(n),
-			ExternalTrafficPolicy: externalTrafficPolicy,
+			Ports:                    servicePorts(n.Spec, s),
+			Selector:                 labelSelector,
+			LoadBalancerIP:           lbIP,
+			LoadBalancerSourceRanges: lbSourceRanges,
+			Type:                     serviceType(s),
+			ExternalTrafficPolicy:    externalTrafficPolicy,
+			SessionAffinity:          sessionAffinity,
+			SessionAffinityConfig:    sessionAffinityConfig,
+			IPFamilies:               ipFamilies,
+			IPFamilyPolicy:           ipFamilyPolicy,
 		},
 	}
 
@@ -260,11 +453,106 @@ func NewService(n *v1alpha1.Nginx) *corev1.Service {
 	return &service
 }
 
-func nginxService(n *v1alpha1.Nginx) corev1.ServiceType {
-	if n == nil || n.Spec.Service == nil {
+// servicePorts returns the Service ports exposing every named nginx port. The
+// http and https ports are served on 80 and 443 while the others keep their
+// container port number, unless the given Service sets a different one.
+func servicePorts(nginxSpec v1alpha1.NginxSpec, s *v1alpha1.NginxService) []corev1.ServicePort {
+	spec := nginxSpec.DeepCopy()
+	spec.SetDefaults()
+
+	overrides := map[string]int32{}
+	if s != nil {
+		for _, p := range s.Ports {
+			overrides[p.Name] = p.Port
+		}
+	}
+
+	var ports []corev1.ServicePort
+	for _, p := range spec.PodTemplate.Ports {
+		if p.Name == "" {
+			continue
+		}
+
+		port := p.ContainerPort
+		switch p.Name {
+		case defaultHTTPPortName:
+			port = 80
+		case defaultHTTPSPortName:
+			port = 443
+		}
+
+		if override, ok := overrides[p.Name]; ok {
+			port = override
+		}
+
+		protocol := p.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+
+		ports = append(ports, corev1.ServicePort{
+			Name:       p.Name,
+			Protocol:   protocol,
+			TargetPort: intstr.FromString(p.Name),
+			Port:       port,
+		})
+	}
+
+	return ports
+}
+
+// httpServicePort returns the port of the http port on the Nginx Service,
+// which may be overridden by spec.service.ports.
+func httpServicePort(nginx *v1alpha1.Nginx) int32 {
+	for _, p := range servicePorts(nginx.Spec, nginx.Spec.Service) {
+		if p.Name == defaultHTTPPortName {
+			return p.Port
+		}
+	}
+
+	return 80
+}
+
+// HeadlessServiceName returns the name of the headless Service of the Nginx
+// with the given name.
+func HeadlessServiceName(name string) string {
+	return name + "-headless"
+}
+
+// NewHeadlessService assembles the headless Service which resolves to the
+// addresses of every Nginx pod.
+func NewHeadlessService(n *v1alpha1.Nginx) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      HeadlessServiceName(n.Name),
+			Namespace: n.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(n, schema.GroupVersionKind{
+					Group:   v1alpha1.GroupVersion.Group,
+					Version: v1alpha1.GroupVersion.Version,
+					Kind:    "Nginx",
+				}),
+			},
+			Labels: LabelsForNginx(n.Name),
+		},
+		Spec: corev1.ServiceSpec{
+			Type:      corev1.ServiceTypeClusterIP,
+			ClusterIP: corev1.ClusterIPNone,
+			Ports:     servicePorts(n.Spec, nil),
+			Selector:  LabelsForNginx(n.Name),
+		},
+	}
+}
+
+func serviceType(s *v1alpha1.NginxService) corev1.ServiceType {
+	if s == nil || s.Type == "" {
 		return corev1.ServiceTypeClusterIP
 	}
-	return corev1.ServiceType(n.Spec.Service.Type)
+	return corev1.ServiceType(s.Type)
 }
 
 // LabelsForNginx returns the labels for a Nginx CR with the given name
@@ -275,6 +563,15 @@ func LabelsForNginx(name string) map[string]string {
 	}
 }
 
+// LabelsForConfigValidation returns the labels of the configuration
+// validation Jobs of a Nginx resource, which must not match its pods.
+func LabelsForConfigValidation(name string) map[string]string {
+	return map[string]string{
+		"nginx.tsuru.io/resource-name": name,
+		"nginx.tsuru.io/app":           "nginx-config-validation",
+	}
+}
+
 // LabelsForNginxString returns the labels in string format.
 func LabelsForNginxString(name string) string {
 	return k8slabels.FormatLabels(LabelsForNginx(name))
@@ -322,14 +619,48 @@ func NewIngress(nginx *v1alpha1.Nginx) *networkingv1.Ingress {
 	}
 
 	var ingressClass *string
+	var extraHosts []string
+	path := "/"
 	if nginx.Spec.Ingress != nil {
 		ingressClass = nginx.Spec.Ingress.IngressClassName
+		extraHosts = nginx.Spec.Ingress.Hosts
+		path = valueOrDefault(nginx.Spec.Ingress.Path, path)
 	}
 
 	var rules []networkingv1.IngressRule
 	var tls []networkingv1.IngressTLS
 	serviceName := fmt.Sprintf("%s-service", nginx.Name)
 
+	hostsWithRule := make(map[string]bool)
+	addRule := func(host string) {
+		if hostsWithRule[host] {
+			return
+		}
+		hostsWithRule[host] = true
+
+		rules = append(rules, networkingv1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{
+						{
+							Path:     path,
+							PathType: func(pt networkingv1.PathType) *networkingv1.PathType { return &pt }(networkingv1.PathTypePrefix),
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: serviceName,
+									Port: networkingv1.ServiceBackendPort{
+										Name: defaultHTTPPortName,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+
 	for _, t := range nginx.Spec.TLS {
 		hosts := t.Hosts
 		if len(hosts) == 0 {
@@ -339,27 +670,7 @@ func NewIngress(nginx *v1alpha1.Nginx) *networkingv1.Ingress {
 		}
 
 		for _, host := range hosts {
-			rules = append(rules, networkingv1.IngressRule{
-				Host: host,
-				IngressRuleValue: networkingv1.IngressRuleValue{
-					HTTP: &networkingv1.HTTPIngressRuleValue{
-						Paths: []networkingv1.HTTPIngressPath{
-							{
-								Path:     "/",
-								PathType: func(pt networkingv1.PathType) *networkingv1.PathType { return &pt }(networkingv1.PathTypePrefix),
-								Backend: networkingv1.IngressBackend{
-									Service: &networkingv1.IngressServiceBackend{
-										Name: serviceName,
-										Port: networkingv1.ServiceBackendPort{
-											Name: defaultHTTPPortName,
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			})
+			addRule(host)
 		}
 
 		tls = append(tls, networkingv1.IngressTLS{
@@ -368,6 +679,10 @@ func NewIngress(nginx *v1alpha1.Nginx) *networkingv1.Ingress {
 		})
 	}
 
+	for _, host := range extraHosts {
+		addRule(host)
+	}
+
 	return &networkingv1.Ingress{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "networking.k8s.io/v1",
@@ -402,6 +717,421 @@ func NewIngress(nginx *v1alpha1.Nginx) *networkingv1.Ingress {
 	}
 }
 
+// NewHTTPRoute assembles the Gateway API HTTPRoute for the Nginx. It's built as
+// an unstructured object so the Gateway API types are only required in the
+// clusters where the HTTPRoute is actually managed.
+func NewHTTPRoute(nginx *v1alpha1.Nginx) *unstructured.Unstructured {
+	labels := LabelsForNginx(nginx.Name)
+	var annotations map[string]string
+	var parentRefs, hostnames []interface{}
+
+	if nginx.Spec.Gateway != nil {
+		labels = mergeMap(nginx.Spec.Gateway.Labels, labels)
+		annotations = mergeMap(nginx.Spec.Gateway.Annotations, annotations)
+
+		for _, ref := range nginx.Spec.Gateway.ParentRefs {
+			parentRef := map[string]interface{}{
+				"group": HTTPRouteGroupVersionKind.Group,
+				"kind":  "Gateway",
+				"name":  ref.Name,
+			}
+
+			if ref.Namespace != "" {
+				parentRef["namespace"] = ref.Namespace
+			}
+
+			if ref.SectionName != "" {
+				parentRef["sectionName"] = ref.SectionName
+			}
+
+			parentRefs = append(parentRefs, parentRef)
+		}
+
+		for _, hostname := range nginx.Spec.Gateway.Hostnames {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+
+	spec := map[string]interface{}{
+		"parentRefs": parentRefs,
+		"rules": []interface{}{
+			map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":  "PathPrefix",
+							"value": "/",
+						},
+					},
+				},
+				"backendRefs": []interface{}{
+					map[string]interface{}{
+						"group":  "",
+						"kind":   "Service",
+						"name":   fmt.Sprintf("%s-service", nginx.Name),
+						"port":   int64(httpServicePort(nginx)),
+						"weight": int64(1),
+					},
+				},
+			},
+		},
+	}
+
+	if len(hostnames) > 0 {
+		spec["hostnames"] = hostnames
+	}
+
+	route := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	route.SetGroupVersionKind(HTTPRouteGroupVersionKind)
+	route.SetName(nginx.Name)
+	route.SetNamespace(nginx.Namespace)
+	route.SetLabels(labels)
+	route.SetAnnotations(annotations)
+	route.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(nginx, schema.GroupVersionKind{
+			Group:   v1alpha1.GroupVersion.Group,
+			Version: v1alpha1.GroupVersion.Version,
+			Kind:    "Nginx",
+		}),
+	})
+
+	return route
+}
+
+// NewServiceMonitor assembles the Prometheus Operator ServiceMonitor which
+// scrapes the metrics port of the Nginx's Service.
+func NewServiceMonitor(nginx *v1alpha1.Nginx) *unstructured.Unstructured {
+	labels := LabelsForNginx(nginx.Name)
+	endpoint := map[string]interface{}{
+		"port": defaultMetricsPortName,
+	}
+
+	if m := nginx.Spec.Metrics; m != nil && m.ServiceMonitor != nil {
+		labels = mergeMap(mergeMap(map[string]string{}, m.ServiceMonitor.Labels), labels)
+
+		if m.ServiceMonitor.Interval != "" {
+			endpoint["interval"] = m.ServiceMonitor.Interval
+		}
+	}
+
+	matchLabels := map[string]interface{}{}
+	for k, v := range LabelsForNginx(nginx.Name) {
+		matchLabels[k] = v
+	}
+
+	sm := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{
+				"matchLabels": matchLabels,
+			},
+			"endpoints": []interface{}{endpoint},
+		},
+	}}
+	sm.SetGroupVersionKind(ServiceMonitorGroupVersionKind)
+	sm.SetName(nginx.Name)
+	sm.SetNamespace(nginx.Namespace)
+	sm.SetLabels(labels)
+	sm.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(nginx, schema.GroupVersionKind{
+			Group:   v1alpha1.GroupVersion.Group,
+			Version: v1alpha1.GroupVersion.Version,
+			Kind:    "Nginx",
+		}),
+	})
+
+	return sm
+}
+
+// NewHorizontalPodAutoscaler assembles the HorizontalPodAutoscaler which scales
+// the Nginx's Deployment.
+func NewHorizontalPodAutoscaler(nginx *v1alpha1.Nginx) *autoscalingv2.HorizontalPodAutoscaler {
+	var spec autoscalingv2.HorizontalPodAutoscalerSpec
+	if a := nginx.Spec.Autoscaling; a != nil {
+		minReplicas := int32(1)
+		if a.MinReplicas != nil {
+			minReplicas = *a.MinReplicas
+		}
+
+		targetCPU := a.TargetCPUUtilizationPercentage
+		if targetCPU == nil && a.TargetMemoryUtilizationPercentage == nil {
+			targetCPU = func(n int32) *int32 { return &n }(defaultTargetCPUUtilizationPercentage)
+		}
+
+		var metrics []autoscalingv2.MetricSpec
+		if targetCPU != nil {
+			metrics = append(metrics, resourceMetric(corev1.ResourceCPU, *targetCPU))
+		}
+
+		if a.TargetMemoryUtilizationPercentage != nil {
+			metrics = append(metrics, resourceMetric(corev1.ResourceMemory, *a.TargetMemoryUtilizationPercentage))
+		}
+
+		spec = autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       nginx.Name,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: a.MaxReplicas,
+			Metrics:     metrics,
+		}
+	}
+
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "autoscaling/v2",
+			Kind:       "HorizontalPodAutoscaler",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nginx.Name,
+			Namespace: nginx.Namespace,
+			Labels:    LabelsForNginx(nginx.Name),
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(nginx, schema.GroupVersionKind{
+					Group:   v1alpha1.GroupVersion.Group,
+					Version: v1alpha1.GroupVersion.Version,
+					Kind:    "Nginx",
+				}),
+			},
+		},
+		Spec: spec,
+	}
+}
+
+// NewConfigValidationJob assembles a Job which checks the nginx configuration
+// (nginx -t) using the same image, files and volumes of the given Deployment.
+func NewConfigValidationJob(n *v1alpha1.Nginx, dep *appv1.Deployment, name string) *batchv1.Job {
+	podSpec := dep.Spec.Template.Spec.DeepCopy()
+
+	nginxContainer := podSpec.Containers[0]
+	nginxContainer.Command = []string{"nginx", "-t"}
+	nginxContainer.Args = nil
+	nginxContainer.Ports = nil
+	nginxContainer.Lifecycle = nil
+	nginxContainer.LivenessProbe = nil
+	nginxContainer.ReadinessProbe = nil
+	nginxContainer.StartupProbe = nil
+
+	podSpec.Containers = []corev1.Container{nginxContainer}
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+	podSpec.HostNetwork = false
+
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: n.Namespace,
+			Labels:    LabelsForConfigValidation(n.Name),
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(n, schema.GroupVersionKind{
+					Group:   v1alpha1.GroupVersion.Group,
+					Version: v1alpha1.GroupVersion.Version,
+					Kind:    "Nginx",
+				}),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          func(n int32) *int32 { return &n }(0),
+			ActiveDeadlineSeconds: func(n int64) *int64 { return &n }(configValidationDeadlineSeconds),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      LabelsForConfigValidation(n.Name),
+					Annotations: dep.Spec.Template.Annotations,
+				},
+				Spec: *podSpec,
+			},
+		},
+	}
+}
+
+// NewServiceAccount assembles the ServiceAccount used by the Nginx pods.
+func NewServiceAccount(nginx *v1alpha1.Nginx) *corev1.ServiceAccount {
+	labels := LabelsForNginx(nginx.Name)
+	var annotations map[string]string
+	var imagePullSecrets []corev1.LocalObjectReference
+	if sa := nginx.Spec.ServiceAccount; sa != nil {
+		labels = mergeMap(mergeMap(map[string]string{}, sa.Labels), labels)
+		annotations = sa.Annotations
+		imagePullSecrets = sa.ImagePullSecrets
+	}
+
+	return &corev1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ServiceAccount",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        nginx.Name,
+			Namespace:   nginx.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(nginx, schema.GroupVersionKind{
+					Group:   v1alpha1.GroupVersion.Group,
+					Version: v1alpha1.GroupVersion.Version,
+					Kind:    "Nginx",
+				}),
+			},
+		},
+		ImagePullSecrets: imagePullSecrets,
+	}
+}
+
+// NewPodDisruptionBudget assembles the PodDisruptionBudget for the Nginx pods.
+func NewPodDisruptionBudget(nginx *v1alpha1.Nginx) *policyv1.PodDisruptionBudget {
+	spec := policyv1.PodDisruptionBudgetSpec{
+		Selector: &metav1.LabelSelector{
+			MatchLabels: LabelsForNginx(nginx.Name),
+		},
+	}
+
+	if pdb := nginx.Spec.PodDisruptionBudget; pdb != nil {
+		spec.MinAvailable = pdb.MinAvailable
+		spec.MaxUnavailable = pdb.MaxUnavailable
+	}
+
+	return &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "policy/v1",
+			Kind:       "PodDisruptionBudget",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nginx.Name,
+			Namespace: nginx.Namespace,
+			Labels:    LabelsForNginx(nginx.Name),
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(nginx, schema.GroupVersionKind{
+					Group:   v1alpha1.GroupVersion.Group,
+					Version: v1alpha1.GroupVersion.Version,
+					Kind:    "Nginx",
+				}),
+			},
+		},
+		Spec: spec,
+	}
+}
+
+// NewNetworkPolicy assembles the NetworkPolicy which restricts the traffic of
+// the Nginx pods.
+func NewNetworkPolicy(nginx *v1alpha1.Nginx) *networkingv1.NetworkPolicy {
+	spec := nginx.Spec.DeepCopy()
+	spec.SetDefaults()
+
+	var ports []networkingv1.NetworkPolicyPort
+	for _, p := range spec.PodTemplate.Ports {
+		ports = append(ports, networkPolicyPort(p.Protocol, p.ContainerPort))
+	}
+
+	if m := spec.Metrics; m != nil && m.Enabled {
+		ports = append(ports, networkPolicyPort(corev1.ProtocolTCP, metricsPort(m)))
+	}
+
+	egress := []networkingv1.NetworkPolicyEgressRule{
+		{
+			Ports: []networkingv1.NetworkPolicyPort{
+				networkPolicyPort(corev1.ProtocolUDP, 53),
+				networkPolicyPort(corev1.ProtocolTCP, 53),
+			},
+		},
+	}
+
+	var from []networkingv1.NetworkPolicyPeer
+	if np := spec.NetworkPolicy; np != nil {
+		from = np.From
+		egress = append(egress, np.Egress...)
+	}
+
+	return &networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "networking.k8s.io/v1",
+			Kind:       "NetworkPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nginx.Name,
+			Namespace: nginx.Namespace,
+			Labels:    LabelsForNginx(nginx.Name),
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(nginx, schema.GroupVersionKind{
+					Group:   v1alpha1.GroupVersion.Group,
+					Version: v1alpha1.GroupVersion.Version,
+					Kind:    "Nginx",
+				}),
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: LabelsForNginx(nginx.Name),
+			},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					Ports: ports,
+					From:  from,
+				},
+			},
+			Egress:      egress,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+}
+
+func networkPolicyPort(protocol corev1.Protocol, port int32) networkingv1.NetworkPolicyPort {
+	if protocol == "" {
+		protocol = corev1.ProtocolTCP
+	}
+
+	p := intstr.FromInt(int(port))
+	return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &p}
+}
+
+func resourceMetric(name corev1.ResourceName, averageUtilization int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: &averageUtilization,
+			},
+		},
+	}
+}
+
+// renderConfigTemplate executes the config template of the Nginx, failing on
+// references to missing keys.
+func renderConfigTemplate(n *v1alpha1.Nginx) (string, error) {
+	tmpl, err := template.New("nginx.conf").Option("missingkey=error").Parse(n.Spec.Config.Value)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse config template: %w", err)
+	}
+
+	env := map[string]string{}
+	for _, e := range n.Spec.PodTemplate.Env {
+		if e.ValueFrom == nil {
+			env[e.Name] = e.Value
+		}
+	}
+
+	data := map[string]interface{}{
+		"Name":        n.Name,
+		"Namespace":   n.Namespace,
+		"Labels":      n.Labels,
+		"Annotations": n.Annotations,
+		"Env":         env,
+		"Values":      n.Spec.Config.Values,
+	}
+
+	var b strings.Builder
+	if err = tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render config template: %w", err)
+	}
+
+	return b.String(), nil
+}
+
 func setupConfig(conf *v1alpha1.ConfigRef, dep *appv1.Deployment) {
 	if conf == nil {
 		return
@@ -435,7 +1165,7 @@ func setupConfig(conf *v1alpha1.ConfigRef, dep *appv1.Deployment) {
 			dep.Spec.Template.Annotations = make(map[string]string)
 		}
 
-		key := "nginx.tsuru.io/custom-nginx-config"
+		key := CustomNginxConfigAnnotation
 		dep.Spec.Template.Annotations[key] = conf.Value
 
 		dep.Spec.Template.Spec.Volumes = append(dep.Spec.Template.Spec.Volumes, corev1.Volume{
@@ -477,9 +1207,61 @@ func setupTLS(tls []v1alpha1.NginxTLS, dep *appv1.Deployment) {
 			ReadOnly:  true,
 		})
 	}
+
+	mountedCAs := make(map[string]bool)
+	for _, t := range tls {
+		if t.ClientAuth == nil || mountedCAs[t.ClientAuth.CASecretName] {
+			continue
+		}
+
+		volumeName := fmt.Sprintf("nginx-client-ca-%d", len(mountedCAs))
+		mountedCAs[t.ClientAuth.CASecretName] = true
+
+		dep.Spec.Template.Spec.Volumes = append(dep.Spec.Template.Spec.Volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: t.ClientAuth.CASecretName,
+					Items:      []corev1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}},
+					Optional:   func(b bool) *bool { return &b }(false),
+				},
+			},
+		})
+
+		dep.Spec.Template.Spec.Containers[0].VolumeMounts = append(dep.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      volumeName,
+			MountPath: filepath.Join(clientCAMountPath, t.ClientAuth.CASecretName),
+			ReadOnly:  true,
+		})
+	}
 }
 
 // setupExtraFiles configures the volume source and mount into Deployment resource.
+func setupConfigFiles(conf *v1alpha1.ConfigRef, dep *appv1.Deployment) {
+	if conf == nil || conf.ExtraFiles == nil || len(conf.ExtraFiles.Sources) == 0 {
+		return
+	}
+
+	var sources []corev1.VolumeProjection
+	for _, src := range conf.ExtraFiles.Sources {
+		sources = append(sources, corev1.VolumeProjection{ConfigMap: src.ConfigMap, Secret: src.Secret})
+	}
+
+	volumeName := "nginx-config-files"
+	dep.Spec.Template.Spec.Containers[0].VolumeMounts = append(dep.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      volumeName,
+		MountPath: valueOrDefault(conf.ExtraFiles.MountPath, defaultConfigFilesMountPath),
+		ReadOnly:  true,
+	})
+
+	dep.Spec.Template.Spec.Volumes = append(dep.Spec.Template.Spec.Volumes, corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{Sources: sources},
+		},
+	})
+}
+
 func setupExtraFiles(fRef *v1alpha1.FilesRef, dep *appv1.Deployment) {
 	if fRef == nil {
 		return
@@ -554,6 +1336,20 @@ func setupCacheVolume(cache v1alpha1.NginxCacheSpec, dep *appv1.Deployment) {
 	})
 }
 
+func setupCommand(podTemplate v1alpha1.NginxPodTemplateSpec, dep *appv1.Deployment) {
+	if len(podTemplate.Command) == 0 && len(podTemplate.Args) == 0 {
+		return
+	}
+
+	command := podTemplate.Command
+	if len(command) == 0 {
+		command = defaultNginxCommand
+	}
+
+	dep.Spec.Template.Spec.Containers[0].Command = nginxCustomEntrypoint
+	dep.Spec.Template.Spec.Containers[0].Args = append(append([]string{}, command...), podTemplate.Args...)
+}
+
 func setupLifecycle(lifecycle *v1alpha1.NginxLifecycle, dep *appv1.Deployment) {
 	defaultLifecycle := corev1.Lifecycle{
 		PostStart: &corev1.LifecycleHandler{
@@ -568,6 +1364,12 @@ func setupLifecycle(lifecycle *v1alpha1.NginxLifecycle, dep *appv1.Deployment) {
 	}
 	if lifecycle.PreStop != nil && lifecycle.PreStop.Exec != nil {
 		dep.Spec.Template.Spec.Containers[0].Lifecycle.PreStop = &corev1.LifecycleHandler{Exec: lifecycle.PreStop.Exec}
+	} else if lifecycle.GracefulShutdown != nil {
+		dep.Spec.Template.Spec.Containers[0].Lifecycle.PreStop = &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"/bin/sh", "-c", fmt.Sprintf(gracefulShutdownCommand, lifecycle.GracefulShutdown.SleepSeconds)},
+			},
+		}
 	}
 	if lifecycle.PostStart != nil && lifecycle.PostStart.Exec != nil {
 		var postStartCommand []string
@@ -588,6 +1390,95 @@ func setupLifecycle(lifecycle *v1alpha1.NginxLifecycle, dep *appv1.Deployment) {
 	}
 }
 
+func setupMetrics(nginxSpec v1alpha1.NginxSpec, dep *appv1.Deployment) {
+	m := nginxSpec.Metrics
+	if m == nil || !m.Enabled {
+		return
+	}
+
+	httpPort := defaultHTTPPort
+	if p := portByName(nginxSpec.PodTemplate.Ports, defaultHTTPPortName); p != nil {
+		httpPort = p.ContainerPort
+	}
+
+	port := metricsPort(m)
+	dep.Spec.Template.Spec.Containers = append(dep.Spec.Template.Spec.Containers, corev1.Container{
+		Name:  metricsExporterContainerName,
+		Image: valueOrDefault(m.Image, defaultMetricsExporterImage),
+		Args: []string{
+			fmt.Sprintf("-nginx.scrape-uri=http://localhost:%d%s", httpPort, valueOrDefault(m.StubStatusPath, defaultStubStatusPath)),
+			fmt.Sprintf("-web.listen-address=:%d", port),
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          defaultMetricsPortName,
+				ContainerPort: port,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		},
+		Resources: m.Resources,
+	})
+}
+
+func setupLogShipper(logging *v1alpha1.NginxLogging, dep *appv1.Deployment) {
+	if logging == nil || logging.Sidecar == nil {
+		return
+	}
+
+	const logsVolumeName, configVolumeName = "nginx-logs", "log-shipper-config"
+
+	sidecar := logging.Sidecar
+	logsMount := corev1.VolumeMount{
+		Name:      logsVolumeName,
+		MountPath: valueOrDefault(sidecar.LogsPath, defaultLogsPath),
+	}
+
+	dep.Spec.Template.Spec.Volumes = append(dep.Spec.Template.Spec.Volumes,
+		corev1.Volume{
+			Name: logsVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+		corev1.Volume{
+			Name: configVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: sidecar.ConfigMapName,
+					},
+				},
+			},
+		},
+	)
+
+	dep.Spec.Template.Spec.Containers[0].VolumeMounts = append(dep.Spec.Template.Spec.Containers[0].VolumeMounts, logsMount)
+	dep.Spec.Template.Spec.Containers = append(dep.Spec.Template.Spec.Containers, corev1.Container{
+		Name:  logShipperContainerName,
+		Image: valueOrDefault(sidecar.Image, defaultLogShipperImage),
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      logsVolumeName,
+				MountPath: logsMount.MountPath,
+				ReadOnly:  true,
+			},
+			{
+				Name:      configVolumeName,
+				MountPath: valueOrDefault(sidecar.ConfigPath, defaultLogShipperConfigPath),
+				ReadOnly:  true,
+			},
+		},
+		Resources: sidecar.Resources,
+	})
+}
+
+func metricsPort(m *v1alpha1.NginxMetrics) int32 {
+	if m.Port == 0 {
+		return defaultMetricsPort
+	}
+	return m.Port
+}
+
 func portByName(ports []corev1.ContainerPort, name string) *corev1.ContainerPort {
 	for i, port := range ports {
 		if port.Name == name {
@@ -597,33 +1488,12 @@ func portByName(ports []corev1.ContainerPort, name string) *corev1.ContainerPort
 	return nil
 }
 
-func setDefaultPorts(podSpec *v1alpha1.NginxPodTemplateSpec) {
-	if portByName(podSpec.Ports, defaultHTTPPortName) == nil {
-		httpPort := defaultHTTPPort
-		if podSpec.HostNetwork {
-			httpPort = defaultHTTPHostNetworkPort
-		}
-		podSpec.Ports = append(podSpec.Ports, corev1.ContainerPort{
-			Name:          defaultHTTPPortName,
-			ContainerPort: httpPort,
-			Protocol:      corev1.ProtocolTCP,
-		})
-	}
-
-	if portByName(podSpec.Ports, defaultHTTPSPortName) == nil {
-		httpsPort := defaultHTTPSPort
-		if podSpec.HostNetwork {
-			httpsPort = defaultHTTPSHostNetworkPort
-		}
-		podSpec.Ports = append(podSpec.Ports, corev1.ContainerPort{
-			Name:          defaultHTTPSPortName,
-			ContainerPort: httpsPort,
-			Protocol:      corev1.ProtocolTCP,
-		})
+func setupProbes(nginxSpec v1alpha1.NginxSpec, dep *appv1.Deployment) {
+	if hc := nginxSpec.Healthcheck; hc != nil {
+		setupHealthcheckProbes(hc, valueOrDefault(hc.Path, valueOrDefault(nginxSpec.HealthcheckPath, "/")), dep)
+		return
 	}
-}
 
-func setupProbes(nginxSpec v1alpha1.NginxSpec, dep *appv1.Deployment) {
 	httpPort := portByName(nginxSpec.PodTemplate.Ports, defaultHTTPPortName)
 	cmdTimeoutSec := int32(1)
 
@@ -658,6 +1528,50 @@ func setupProbes(nginxSpec v1alpha1.NginxSpec, dep *appv1.Deployment) {
 	}
 }
 
+func setupHealthcheckProbes(hc *v1alpha1.NginxHealthcheck, path string, dep *appv1.Deployment) {
+	port := intstr.FromString(defaultHTTPPortName)
+	if hc.Port != nil {
+		port = *hc.Port
+	}
+
+	scheme := hc.Scheme
+	if scheme == "" {
+		scheme = corev1.URISchemeHTTP
+	}
+
+	newProbe := func(settings *v1alpha1.NginxProbe) *corev1.Probe {
+		probe := &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path:   path,
+					Port:   port,
+					Scheme: scheme,
+				},
+			},
+		}
+
+		if settings != nil {
+			probe.InitialDelaySeconds = settings.InitialDelaySeconds
+			probe.TimeoutSeconds = settings.TimeoutSeconds
+			probe.PeriodSeconds = settings.PeriodSeconds
+			probe.SuccessThreshold = settings.SuccessThreshold
+			probe.FailureThreshold = settings.FailureThreshold
+		}
+
+		return probe
+	}
+
+	dep.Spec.Template.Spec.Containers[0].ReadinessProbe = newProbe(hc.Readiness)
+
+	if hc.Liveness != nil {
+		dep.Spec.Template.Spec.Containers[0].LivenessProbe = newProbe(hc.Liveness)
+	}
+
+	if hc.Startup != nil {
+		dep.Spec.Template.Spec.Containers[0].StartupProbe = newProbe(hc.Startup)
+	}
+}
+
 func hasLowPort(ports []corev1.ContainerPort) bool {
 	for _, port := range ports {
 		if port.ContainerPort < 1024 {
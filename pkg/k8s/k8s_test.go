@@ -12,8 +12,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	appv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -166,6 +168,243 @@ func Test_NewDeployment(t *testing.T) {
 				return d
 			},
 		},
+		{
+			name: "with-autoscaling",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				v := int32(3)
+				n.Spec.Replicas = &v
+				n.Spec.Autoscaling = &v1alpha1.NginxAutoscaling{MaxReplicas: 10}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				return d
+			},
+		},
+		{
+			name: "with-managed-service-account",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.ServiceAccount = &v1alpha1.NginxServiceAccount{}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.ServiceAccountName = "my-nginx"
+				return d
+			},
+		},
+		{
+			name: "with-custom-healthcheck",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.HealthcheckPath = "/healthz"
+				n.Spec.Healthcheck = &v1alpha1.NginxHealthcheck{
+					Port:      func(v intstr.IntOrString) *intstr.IntOrString { return &v }(intstr.FromInt(9090)),
+					Readiness: &v1alpha1.NginxProbe{PeriodSeconds: 5, FailureThreshold: 2},
+					Liveness:  &v1alpha1.NginxProbe{InitialDelaySeconds: 10, TimeoutSeconds: 3},
+				}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path:   "/healthz",
+							Port:   intstr.FromInt(9090),
+							Scheme: corev1.URISchemeHTTP,
+						},
+					},
+					PeriodSeconds:    5,
+					FailureThreshold: 2,
+				}
+				d.Spec.Template.Spec.Containers[0].LivenessProbe = &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path:   "/healthz",
+							Port:   intstr.FromInt(9090),
+							Scheme: corev1.URISchemeHTTP,
+						},
+					},
+					InitialDelaySeconds: 10,
+					TimeoutSeconds:      3,
+				}
+				return d
+			},
+		},
+		{
+			name: "with-metrics",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.Metrics = &v1alpha1.NginxMetrics{Enabled: true, StubStatusPath: "/basic_status"}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, corev1.Container{
+					Name:  "nginx-prometheus-exporter",
+					Image: "nginx/nginx-prometheus-exporter:0.11.0",
+					Args: []string{
+						"-nginx.scrape-uri=http://localhost:8080/basic_status",
+						"-web.listen-address=:9113",
+					},
+					Ports: []corev1.ContainerPort{
+						{Name: "metrics", ContainerPort: 9113, Protocol: corev1.ProtocolTCP},
+					},
+				})
+				return d
+			},
+		},
+		{
+			name: "with-log-shipper-sidecar",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.Logging = &v1alpha1.NginxLogging{
+					Sidecar: &v1alpha1.NginxLoggingSidecar{ConfigMapName: "fluent-bit-config"},
+				}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Volumes = []corev1.Volume{
+					{
+						Name: "nginx-logs",
+						VolumeSource: corev1.VolumeSource{
+							EmptyDir: &corev1.EmptyDirVolumeSource{},
+						},
+					},
+					{
+						Name: "log-shipper-config",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "fluent-bit-config"},
+							},
+						},
+					},
+				}
+				d.Spec.Template.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+					{Name: "nginx-logs", MountPath: "/var/log/nginx"},
+				}
+				d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, corev1.Container{
+					Name:  "log-shipper",
+					Image: "fluent/fluent-bit:1.9",
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "nginx-logs", MountPath: "/var/log/nginx", ReadOnly: true},
+						{Name: "log-shipper-config", MountPath: "/fluent-bit/etc", ReadOnly: true},
+					},
+				})
+				return d
+			},
+		},
+		{
+			name: "with-metrics-disabled",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.Metrics = &v1alpha1.NginxMetrics{Port: 9000}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				return d
+			},
+		},
+		{
+			name: "with-recreate-strategy",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.PodTemplate.HostNetwork = true
+				n.Spec.Strategy = &v1alpha1.NginxStrategy{Type: appv1.RecreateDeploymentStrategyType}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Strategy = appv1.DeploymentStrategy{Type: appv1.RecreateDeploymentStrategyType}
+				d.Spec.Template.Spec.HostNetwork = true
+				d.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+				d.Spec.Template.Spec.Containers[0].Ports = []corev1.ContainerPort{
+					{
+						Name:          defaultHTTPPortName,
+						ContainerPort: defaultHTTPHostNetworkPort,
+						Protocol:      corev1.ProtocolTCP,
+					},
+					{
+						Name:          defaultHTTPSPortName,
+						ContainerPort: defaultHTTPSHostNetworkPort,
+						Protocol:      corev1.ProtocolTCP,
+					},
+				}
+				d.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+					Capabilities: &corev1.Capabilities{
+						Add: []corev1.Capability{"NET_BIND_SERVICE"},
+					},
+				}
+				d.Spec.Template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
+					TimeoutSeconds: int32(1),
+					ProbeHandler: corev1.ProbeHandler{
+						Exec: &corev1.ExecAction{
+							Command: []string{"sh", "-c", "curl -m1 -kfsS -o /dev/null http://localhost:80"},
+						},
+					},
+				}
+				return d
+			},
+		},
+		{
+			name: "with-rolling-update-strategy",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				zero, one := intstr.FromInt(0), intstr.FromInt(1)
+				n.Spec.PodTemplate.RollingUpdate = &appv1.RollingUpdateDeployment{MaxUnavailable: &one}
+				n.Spec.Strategy = &v1alpha1.NginxStrategy{
+					RollingUpdate: &appv1.RollingUpdateDeployment{MaxUnavailable: &zero, MaxSurge: &one},
+				}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				zero, one := intstr.FromInt(0), intstr.FromInt(1)
+				d.Spec.Strategy.RollingUpdate = &appv1.RollingUpdateDeployment{MaxUnavailable: &zero, MaxSurge: &one}
+				return d
+			},
+		},
+		{
+			name: "with-startup-probe",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.Healthcheck = &v1alpha1.NginxHealthcheck{
+					Path:    "/ready",
+					Startup: &v1alpha1.NginxProbe{PeriodSeconds: 10, FailureThreshold: 30},
+				}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path:   "/ready",
+							Port:   intstr.FromString("http"),
+							Scheme: corev1.URISchemeHTTP,
+						},
+					},
+				}
+				d.Spec.Template.Spec.Containers[0].StartupProbe = &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path:   "/ready",
+							Port:   intstr.FromString("http"),
+							Scheme: corev1.URISchemeHTTP,
+						},
+					},
+					PeriodSeconds:    10,
+					FailureThreshold: 30,
+				}
+				return d
+			},
+		},
+		{
+			name: "with-default-healthcheck",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.Healthcheck = &v1alpha1.NginxHealthcheck{}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path:   "/",
+							Port:   intstr.FromString("http"),
+							Scheme: corev1.URISchemeHTTP,
+						},
+					},
+				}
+				return d
+			},
+		},
 		{
 			name: "custom-image",
 			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
@@ -177,6 +416,17 @@ func Test_NewDeployment(t *testing.T) {
 				return d
 			},
 		},
+		{
+			name: "custom-image-pull-policy",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.ImagePullPolicy = corev1.PullAlways
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Containers[0].ImagePullPolicy = corev1.PullAlways
+				return d
+			},
+		},
 		{
 			name: "pod-security-context",
 			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
@@ -277,6 +527,108 @@ func Test_NewDeployment(t *testing.T) {
 				return d
 			},
 		},
+		{
+			name: "with-config-template",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.Config = &v1alpha1.ConfigRef{
+					Kind:   v1alpha1.ConfigKindTemplate,
+					Value:  "server { server_name {{ .Name }}.{{ .Values.domain }}; }",
+					Values: map[string]string{"domain": "example.com"},
+				}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+					{
+						Name:      "nginx-config",
+						MountPath: "/etc/nginx/nginx.conf",
+						SubPath:   "nginx.conf",
+						ReadOnly:  true,
+					},
+				}
+				d.Spec.Template.Annotations = map[string]string{
+					"nginx.tsuru.io/custom-nginx-config": "server { server_name my-nginx.example.com; }",
+				}
+				d.Spec.Template.Spec.Volumes = []corev1.Volume{
+					{
+						Name: "nginx-config",
+						VolumeSource: corev1.VolumeSource{
+							DownwardAPI: &corev1.DownwardAPIVolumeSource{
+								Items: []corev1.DownwardAPIVolumeFile{
+									{
+										Path: "nginx.conf",
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "metadata.annotations['nginx.tsuru.io/custom-nginx-config']",
+										},
+									},
+								},
+							},
+						},
+					},
+				}
+				return d
+			},
+		},
+		{
+			name: "with-config-extra-files",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.Config = &v1alpha1.ConfigRef{
+					Kind: v1alpha1.ConfigKindConfigMap,
+					Name: "my-config",
+					ExtraFiles: &v1alpha1.NginxConfigFiles{
+						Sources: []v1alpha1.NginxConfigFileSource{
+							{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "my-upstreams"}}},
+							{Secret: &corev1.SecretProjection{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "my-htpasswd"},
+								Items:                []corev1.KeyToPath{{Key: "htpasswd", Path: "auth/htpasswd"}},
+							}},
+						},
+					},
+				}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+					{
+						Name:      "nginx-config",
+						MountPath: "/etc/nginx/nginx.conf",
+						SubPath:   "nginx.conf",
+						ReadOnly:  true,
+					},
+					{
+						Name:      "nginx-config-files",
+						MountPath: "/etc/nginx/conf.d",
+						ReadOnly:  true,
+					},
+				}
+				d.Spec.Template.Spec.Volumes = []corev1.Volume{
+					{
+						Name: "nginx-config",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "my-config"},
+								Optional:             func(b bool) *bool { return &b }(false),
+							},
+						},
+					},
+					{
+						Name: "nginx-config-files",
+						VolumeSource: corev1.VolumeSource{
+							Projected: &corev1.ProjectedVolumeSource{
+								Sources: []corev1.VolumeProjection{
+									{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "my-upstreams"}}},
+									{Secret: &corev1.SecretProjection{
+										LocalObjectReference: corev1.LocalObjectReference{Name: "my-htpasswd"},
+										Items:                []corev1.KeyToPath{{Key: "htpasswd", Path: "auth/htpasswd"}},
+									}},
+								},
+							},
+						},
+					},
+				}
+				return d
+			},
+		},
 		{
 			name: "with-tls",
 			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
@@ -325,6 +677,62 @@ func Test_NewDeployment(t *testing.T) {
 				return d
 			},
 		},
+		{
+			name: "with-tls-client-auth",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.TLS = []v1alpha1.NginxTLS{
+					{SecretName: "rsa-cert", ClientAuth: &v1alpha1.NginxTLSClientAuth{CASecretName: "my-ca"}},
+					{SecretName: "ecdsa-cert", ClientAuth: &v1alpha1.NginxTLSClientAuth{CASecretName: "my-ca"}},
+				}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+					{Name: "nginx-certs-0", MountPath: "/etc/nginx/certs/rsa-cert", ReadOnly: true},
+					{Name: "nginx-certs-1", MountPath: "/etc/nginx/certs/ecdsa-cert", ReadOnly: true},
+					{Name: "nginx-client-ca-0", MountPath: "/etc/nginx/client-ca/my-ca", ReadOnly: true},
+				}
+				d.Spec.Template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
+					TimeoutSeconds: int32(2),
+					ProbeHandler: corev1.ProbeHandler{
+						Exec: &corev1.ExecAction{
+							Command: []string{"sh", "-c", "curl -m1 -kfsS -o /dev/null http://localhost:8080 && curl -m1 -kfsS -o /dev/null https://localhost:8443"},
+						},
+					},
+				}
+				d.Spec.Template.Spec.Volumes = []corev1.Volume{
+					{
+						Name: "nginx-certs-0",
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: "rsa-cert",
+								Optional:   func(b bool) *bool { return &b }(false),
+							},
+						},
+					},
+					{
+						Name: "nginx-certs-1",
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: "ecdsa-cert",
+								Optional:   func(b bool) *bool { return &b }(false),
+							},
+						},
+					},
+					{
+						Name: "nginx-client-ca-0",
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: "my-ca",
+								Items:      []corev1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}},
+								Optional:   func(b bool) *bool { return &b }(false),
+							},
+						},
+					},
+				}
+				return d
+			},
+		},
 		{
 			name: "with-two-certificates",
 			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
@@ -391,33 +799,176 @@ func Test_NewDeployment(t *testing.T) {
 			},
 		},
 		{
-			name: "with-affinity",
+			name: "with-affinity",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.PodTemplate.Affinity = &corev1.Affinity{
+					NodeAffinity: &corev1.NodeAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+							NodeSelectorTerms: []corev1.NodeSelectorTerm{
+								{MatchExpressions: []corev1.NodeSelectorRequirement{
+									{Key: "tsuru.io/pool", Values: []string{"my-pool"}},
+								}},
+							},
+						},
+					},
+				}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Affinity = &corev1.Affinity{
+					NodeAffinity: &corev1.NodeAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+							NodeSelectorTerms: []corev1.NodeSelectorTerm{
+								{MatchExpressions: []corev1.NodeSelectorRequirement{
+									{Key: "tsuru.io/pool", Values: []string{"my-pool"}},
+								}},
+							},
+						},
+					},
+				}
+				return d
+			},
+		},
+		{
+			name: "with-default-anti-affinity",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.PodTemplate.DefaultAntiAffinity = &v1alpha1.NginxDefaultAntiAffinity{Required: true}
+				n.Spec.PodTemplate.Affinity = &corev1.Affinity{
+					NodeAffinity: &corev1.NodeAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+							NodeSelectorTerms: []corev1.NodeSelectorTerm{
+								{MatchExpressions: []corev1.NodeSelectorRequirement{
+									{Key: "tsuru.io/pool", Values: []string{"my-pool"}},
+								}},
+							},
+						},
+					},
+				}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				selector := &metav1.LabelSelector{MatchLabels: map[string]string{
+					"nginx.tsuru.io/resource-name": "my-nginx",
+					"nginx.tsuru.io/app":           "nginx",
+				}}
+				d.Spec.Template.Spec.Affinity = &corev1.Affinity{
+					NodeAffinity: &corev1.NodeAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+							NodeSelectorTerms: []corev1.NodeSelectorTerm{
+								{MatchExpressions: []corev1.NodeSelectorRequirement{
+									{Key: "tsuru.io/pool", Values: []string{"my-pool"}},
+								}},
+							},
+						},
+					},
+					PodAntiAffinity: &corev1.PodAntiAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+							{LabelSelector: selector, TopologyKey: "kubernetes.io/hostname"},
+						},
+						PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+							{Weight: 50, PodAffinityTerm: corev1.PodAffinityTerm{LabelSelector: selector, TopologyKey: "topology.kubernetes.io/zone"}},
+						},
+					},
+				}
+				return d
+			},
+		},
+		{
+			name: "with-args",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.PodTemplate.Args = []string{"-g", "daemon off; worker_processes 8;"}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Containers[0].Command = []string{
+					"/bin/sh",
+					"-c",
+					`while ! [ -f /tmp/done ]; do [ -f /tmp/error ] && cat /tmp/error >&2; sleep 0.5; done && exec "$@"`,
+					"sh",
+				}
+				d.Spec.Template.Spec.Containers[0].Args = []string{"nginx", "-g", "daemon off; worker_processes 8;"}
+				return d
+			},
+		},
+		{
+			name: "with-command",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.PodTemplate.Command = []string{"/docker-entrypoint.sh"}
+				n.Spec.PodTemplate.Args = []string{"nginx", "-g", "daemon off;"}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Containers[0].Command = []string{
+					"/bin/sh",
+					"-c",
+					`while ! [ -f /tmp/done ]; do [ -f /tmp/error ] && cat /tmp/error >&2; sleep 0.5; done && exec "$@"`,
+					"sh",
+				}
+				d.Spec.Template.Spec.Containers[0].Args = []string{"/docker-entrypoint.sh", "nginx", "-g", "daemon off;"}
+				return d
+			},
+		},
+		{
+			name: "with-env",
 			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
-				n.Spec.PodTemplate.Affinity = &corev1.Affinity{
-					NodeAffinity: &corev1.NodeAffinity{
-						RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
-							NodeSelectorTerms: []corev1.NodeSelectorTerm{
-								{MatchExpressions: []corev1.NodeSelectorRequirement{
-									{Key: "tsuru.io/pool", Values: []string{"my-pool"}},
-								}},
-							},
-						},
-					},
+				n.Spec.PodTemplate.Env = []corev1.EnvVar{{Name: "UPSTREAM_HOST", Value: "app.default.svc"}}
+				n.Spec.PodTemplate.EnvFrom = []corev1.EnvFromSource{
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "my-secret"}}},
 				}
 				return n
 			},
 			deployFn: func(d appv1.Deployment) appv1.Deployment {
-				d.Spec.Template.Spec.Affinity = &corev1.Affinity{
-					NodeAffinity: &corev1.NodeAffinity{
-						RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
-							NodeSelectorTerms: []corev1.NodeSelectorTerm{
-								{MatchExpressions: []corev1.NodeSelectorRequirement{
-									{Key: "tsuru.io/pool", Values: []string{"my-pool"}},
-								}},
-							},
-						},
-					},
+				d.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "UPSTREAM_HOST", Value: "app.default.svc"}}
+				d.Spec.Template.Spec.Containers[0].EnvFrom = []corev1.EnvFromSource{
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "my-secret"}}},
+				}
+				return d
+			},
+		},
+		{
+			name: "with-dns-config-and-host-aliases",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.PodTemplate.DNSPolicy = corev1.DNSNone
+				n.Spec.PodTemplate.DNSConfig = &corev1.PodDNSConfig{
+					Nameservers: []string{"10.1.1.1"},
+					Searches:    []string{"corp.example.com"},
+					Options:     []corev1.PodDNSConfigOption{{Name: "ndots", Value: func(s string) *string { return &s }("2")}},
+				}
+				n.Spec.PodTemplate.HostAliases = []corev1.HostAlias{{IP: "10.2.2.2", Hostnames: []string{"legacy.corp.example.com"}}}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.DNSPolicy = corev1.DNSNone
+				d.Spec.Template.Spec.DNSConfig = &corev1.PodDNSConfig{
+					Nameservers: []string{"10.1.1.1"},
+					Searches:    []string{"corp.example.com"},
+					Options:     []corev1.PodDNSConfigOption{{Name: "ndots", Value: func(s string) *string { return &s }("2")}},
 				}
+				d.Spec.Template.Spec.HostAliases = []corev1.HostAlias{{IP: "10.2.2.2", Hostnames: []string{"legacy.corp.example.com"}}}
+				return d
+			},
+		},
+		{
+			name: "with-image-pull-secrets",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "my-registry"}}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "my-registry"}}
+				return d
+			},
+		},
+		{
+			name: "with-priority-and-runtime-class",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.PodTemplate.PriorityClassName = "system-cluster-critical"
+				n.Spec.PodTemplate.RuntimeClassName = func(s string) *string { return &s }("gvisor")
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.PriorityClassName = "system-cluster-critical"
+				d.Spec.Template.Spec.RuntimeClassName = func(s string) *string { return &s }("gvisor")
 				return d
 			},
 		},
@@ -444,6 +995,7 @@ func Test_NewDeployment(t *testing.T) {
 			},
 			deployFn: func(d appv1.Deployment) appv1.Deployment {
 				d.Spec.Template.Spec.HostNetwork = true
+				d.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
 				d.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
 					Capabilities: &corev1.Capabilities{
 						Add: []corev1.Capability{
@@ -519,6 +1071,7 @@ func Test_NewDeployment(t *testing.T) {
 				zero := int32(0)
 				d.Spec.Replicas = &zero
 				d.Spec.Template.Spec.HostNetwork = true
+				d.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
 				d.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
 					Capabilities: &corev1.Capabilities{
 						Add: []corev1.Capability{
@@ -563,6 +1116,7 @@ func Test_NewDeployment(t *testing.T) {
 			},
 			deployFn: func(d appv1.Deployment) appv1.Deployment {
 				d.Spec.Template.Spec.HostNetwork = true
+				d.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
 				d.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
 					Capabilities: &corev1.Capabilities{
 						Add: []corev1.Capability{
@@ -649,6 +1203,7 @@ func Test_NewDeployment(t *testing.T) {
 			},
 			deployFn: func(d appv1.Deployment) appv1.Deployment {
 				d.Spec.Template.Spec.HostNetwork = true
+				d.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
 				d.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
 					Capabilities: &corev1.Capabilities{
 						Drop: []corev1.Capability{
@@ -796,6 +1351,23 @@ func Test_NewDeployment(t *testing.T) {
 				return d
 			},
 		},
+		{
+			name: "when trying to override the selector labels on PodTemplate",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.PodTemplate.Labels = map[string]string{
+					"nginx.tsuru.io/app":           "other-app",
+					"nginx.tsuru.io/resource-name": "other-nginx",
+					"project":                      "z",
+				}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Labels = mergeMap(map[string]string{
+					"project": "z",
+				}, d.Spec.Template.Labels)
+				return d
+			},
+		},
 		{
 			name: "when adding extra annotations to PodTemplate",
 			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
@@ -903,6 +1475,38 @@ func Test_NewDeployment(t *testing.T) {
 				return d
 			},
 		},
+		{
+			name: "with-lifecycle-graceful-shutdown",
+			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
+				n.Spec.Lifecycle = &v1alpha1.NginxLifecycle{
+					GracefulShutdown: &v1alpha1.NginxGracefulShutdown{SleepSeconds: 5},
+				}
+				return n
+			},
+			deployFn: func(d appv1.Deployment) appv1.Deployment {
+				d.Spec.Template.Spec.Containers[0].Lifecycle = &corev1.Lifecycle{
+					PreStop: &corev1.LifecycleHandler{
+						Exec: &corev1.ExecAction{
+							Command: []string{
+								"/bin/sh",
+								"-c",
+								"sleep 5 && nginx -s quit && while [ -d /proc/1 ]; do sleep 1; done",
+							},
+						},
+					},
+					PostStart: &corev1.LifecycleHandler{
+						Exec: &corev1.ExecAction{
+							Command: []string{
+								"/bin/sh",
+								"-c",
+								"nginx -t | tee /tmp/error && touch /tmp/done",
+							},
+						},
+					},
+				}
+				return d
+			},
+		},
 		{
 			name: "with-lifecycle-poststart-exec",
 			nginxFn: func(n v1alpha1.Nginx) v1alpha1.Nginx {
@@ -1048,6 +1652,7 @@ func Test_NewDeployment(t *testing.T) {
 			},
 			deployFn: func(d appv1.Deployment) appv1.Deployment {
 				d.Spec.Template.Spec.HostNetwork = true
+				d.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
 				d.Spec.Template.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
 					Capabilities: &corev1.Capabilities{
 						Add: []corev1.Capability{
@@ -1101,6 +1706,7 @@ func Test_NewDeployment(t *testing.T) {
 			},
 			deployFn: func(d appv1.Deployment) appv1.Deployment {
 				d.Spec.Template.Spec.HostNetwork = true
+				d.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
 				d.Spec.Template.Spec.Containers[0].Ports = []corev1.ContainerPort{
 					{
 						Name:          defaultHTTPPortName,
@@ -1351,8 +1957,188 @@ func TestNewService(t *testing.T) {
 		want  *corev1.Service
 	}{
 		{
-			name:  "base",
-			nginx: baseNginx(),
+			name:  "base",
+			nginx: baseNginx(),
+			want: &corev1.Service{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "Service",
+					APIVersion: "v1",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx-service",
+					Namespace: "default",
+					Labels: map[string]string{
+						"nginx.tsuru.io/resource-name": "my-nginx",
+						"nginx.tsuru.io/app":           "nginx",
+					},
+					Annotations: map[string]string{},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "http",
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromString("http"),
+							Port:       int32(80),
+						},
+						{
+							Name:       "https",
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromString("https"),
+							Port:       int32(443),
+						},
+					},
+					Selector: map[string]string{
+						"nginx.tsuru.io/resource-name": "my-nginx",
+						"nginx.tsuru.io/app":           "nginx",
+					},
+					Type: corev1.ServiceTypeClusterIP,
+				},
+			},
+		},
+		{
+			name:  "with-service",
+			nginx: nginxWithService(),
+			want: &corev1.Service{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "Service",
+					APIVersion: "v1",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx-service",
+					Namespace: "default",
+					Labels: map[string]string{
+						"nginx.tsuru.io/resource-name": "my-nginx",
+						"nginx.tsuru.io/app":           "nginx",
+					},
+					Annotations: map[string]string{},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "http",
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromString("http"),
+							Port:       int32(80),
+						},
+						{
+							Name:       "https",
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromString("https"),
+							Port:       int32(443),
+						},
+					},
+					Selector: map[string]string{
+						"nginx.tsuru.io/resource-name": "my-nginx",
+						"nginx.tsuru.io/app":           "nginx",
+					},
+					Type: corev1.ServiceTypeLoadBalancer,
+				},
+			},
+		},
+		{
+			name:  "with-tls",
+			nginx: nginxWithCertificate(),
+			want: &corev1.Service{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "Service",
+					APIVersion: "v1",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx-service",
+					Namespace: "default",
+					Labels: map[string]string{
+						"nginx.tsuru.io/resource-name": "my-nginx",
+						"nginx.tsuru.io/app":           "nginx",
+					},
+					Annotations: map[string]string{},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "http",
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromString("http"),
+							Port:       int32(80),
+						},
+						{
+							Name:       "https",
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromString("https"),
+							Port:       int32(443),
+						},
+					},
+					Type: corev1.ServiceTypeClusterIP,
+					Selector: map[string]string{
+						"nginx.tsuru.io/resource-name": "my-nginx",
+						"nginx.tsuru.io/app":           "nginx",
+					},
+				},
+			},
+		},
+		{
+			name: "with-service-extra",
+			nginx: func() v1alpha1.Nginx {
+				n := nginxWithService()
+				n.Spec.Service.LoadBalancerIP = "10.0.0.1"
+				n.Spec.Service.Labels = map[string]string{
+					"x":                  "y",
+					"nginx.tsuru.io/app": "ignored",
+				}
+				n.Spec.Service.Annotations = map[string]string{
+					"a": "b",
+				}
+				return n
+			}(),
+			want: &corev1.Service{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "Service",
+					APIVersion: "v1",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx-service",
+					Namespace: "default",
+					Labels: map[string]string{
+						"nginx.tsuru.io/resource-name": "my-nginx",
+						"nginx.tsuru.io/app":           "nginx",
+						"x":                            "y",
+					},
+					Annotations: map[string]string{
+						"a": "b",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "http",
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromString("http"),
+							Port:       int32(80),
+						},
+						{
+							Name:       "https",
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromString("https"),
+							Port:       int32(443),
+						},
+					},
+					Selector: map[string]string{
+						"nginx.tsuru.io/resource-name": "my-nginx",
+						"nginx.tsuru.io/app":           "nginx",
+					},
+					Type:           corev1.ServiceTypeLoadBalancer,
+					LoadBalancerIP: "10.0.0.1",
+				},
+			},
+		},
+		{
+			name: "using Local externalTrafficPolicy",
+			nginx: func() v1alpha1.Nginx {
+				n := nginxWithService()
+				n.Spec.Service.Type = corev1.ServiceTypeLoadBalancer
+				n.Spec.Service.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+				return n
+			}(),
 			want: &corev1.Service{
 				TypeMeta: metav1.TypeMeta{
 					Kind:       "Service",
@@ -1386,13 +2172,20 @@ func TestNewService(t *testing.T) {
 						"nginx.tsuru.io/resource-name": "my-nginx",
 						"nginx.tsuru.io/app":           "nginx",
 					},
-					Type: corev1.ServiceTypeClusterIP,
+					Type:                  corev1.ServiceTypeLoadBalancer,
+					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
 				},
 			},
 		},
 		{
-			name:  "with-service",
-			nginx: nginxWithService(),
+			name: "with service but without type",
+			nginx: func() v1alpha1.Nginx {
+				n := baseNginx()
+				n.Spec.Service = &v1alpha1.NginxService{
+					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+				}
+				return n
+			}(),
 			want: &corev1.Service{
 				TypeMeta: metav1.TypeMeta{
 					Kind:       "Service",
@@ -1426,13 +2219,22 @@ func TestNewService(t *testing.T) {
 						"nginx.tsuru.io/resource-name": "my-nginx",
 						"nginx.tsuru.io/app":           "nginx",
 					},
-					Type: corev1.ServiceTypeLoadBalancer,
+					Type: corev1.ServiceTypeClusterIP,
 				},
 			},
 		},
 		{
-			name:  "with-tls",
-			nginx: nginxWithCertificate(),
+			name: "with custom ports",
+			nginx: func() v1alpha1.Nginx {
+				n := nginxWithService()
+				n.Spec.PodTemplate.Ports = []corev1.ContainerPort{
+					{Name: "http", ContainerPort: 8080},
+					{Name: "dns", ContainerPort: 5353, Protocol: corev1.ProtocolUDP},
+					{ContainerPort: 9000},
+				}
+				n.Spec.Service.Ports = []v1alpha1.NginxServicePort{{Name: "https", Port: 8443}}
+				return n
+			}(),
 			want: &corev1.Service{
 				TypeMeta: metav1.TypeMeta{
 					Kind:       "Service",
@@ -1455,32 +2257,35 @@ func TestNewService(t *testing.T) {
 							TargetPort: intstr.FromString("http"),
 							Port:       int32(80),
 						},
+						{
+							Name:       "dns",
+							Protocol:   corev1.ProtocolUDP,
+							TargetPort: intstr.FromString("dns"),
+							Port:       int32(5353),
+						},
 						{
 							Name:       "https",
 							Protocol:   corev1.ProtocolTCP,
 							TargetPort: intstr.FromString("https"),
-							Port:       int32(443),
+							Port:       int32(8443),
 						},
 					},
-					Type: corev1.ServiceTypeClusterIP,
 					Selector: map[string]string{
 						"nginx.tsuru.io/resource-name": "my-nginx",
 						"nginx.tsuru.io/app":           "nginx",
 					},
+					Type: corev1.ServiceTypeLoadBalancer,
 				},
 			},
 		},
 		{
-			name: "with-service-extra",
+			name: "with session affinity and source ranges",
 			nginx: func() v1alpha1.Nginx {
 				n := nginxWithService()
-				n.Spec.Service.LoadBalancerIP = "10.0.0.1"
-				n.Spec.Service.Labels = map[string]string{
-					"x":                  "y",
-					"nginx.tsuru.io/app": "ignored",
-				}
-				n.Spec.Service.Annotations = map[string]string{
-					"a": "b",
+				n.Spec.Service.LoadBalancerSourceRanges = []string{"10.0.0.0/8"}
+				n.Spec.Service.SessionAffinity = corev1.ServiceAffinityClientIP
+				n.Spec.Service.SessionAffinityConfig = &corev1.SessionAffinityConfig{
+					ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: func(n int32) *int32 { return &n }(600)},
 				}
 				return n
 			}(),
@@ -1495,11 +2300,8 @@ func TestNewService(t *testing.T) {
 					Labels: map[string]string{
 						"nginx.tsuru.io/resource-name": "my-nginx",
 						"nginx.tsuru.io/app":           "nginx",
-						"x":                            "y",
-					},
-					Annotations: map[string]string{
-						"a": "b",
 					},
+					Annotations: map[string]string{},
 				},
 				Spec: corev1.ServiceSpec{
 					Ports: []corev1.ServicePort{
@@ -1520,17 +2322,20 @@ func TestNewService(t *testing.T) {
 						"nginx.tsuru.io/resource-name": "my-nginx",
 						"nginx.tsuru.io/app":           "nginx",
 					},
-					Type:           corev1.ServiceTypeLoadBalancer,
-					LoadBalancerIP: "10.0.0.1",
+					Type:                     corev1.ServiceTypeLoadBalancer,
+					LoadBalancerSourceRanges: []string{"10.0.0.0/8"},
+					SessionAffinity:          corev1.ServiceAffinityClientIP,
+					SessionAffinityConfig: &corev1.SessionAffinityConfig{
+						ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: func(n int32) *int32 { return &n }(600)},
+					},
 				},
 			},
 		},
 		{
-			name: "using Local externalTrafficPolicy",
+			name: "with metrics",
 			nginx: func() v1alpha1.Nginx {
-				n := nginxWithService()
-				n.Spec.Service.Type = corev1.ServiceTypeLoadBalancer
-				n.Spec.Service.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+				n := baseNginx()
+				n.Spec.Metrics = &v1alpha1.NginxMetrics{Enabled: true, Port: 9000}
 				return n
 			}(),
 			want: &corev1.Service{
@@ -1561,13 +2366,18 @@ func TestNewService(t *testing.T) {
 							TargetPort: intstr.FromString("https"),
 							Port:       int32(443),
 						},
+						{
+							Name:       "metrics",
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromString("metrics"),
+							Port:       int32(9000),
+						},
 					},
 					Selector: map[string]string{
 						"nginx.tsuru.io/resource-name": "my-nginx",
 						"nginx.tsuru.io/app":           "nginx",
 					},
-					Type:                  corev1.ServiceTypeLoadBalancer,
-					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+					Type: corev1.ServiceTypeClusterIP,
 				},
 			},
 		},
@@ -1626,6 +2436,53 @@ func TestNewService(t *testing.T) {
 	}
 }
 
+func TestNewDeployment_configTemplateMissingKey(t *testing.T) {
+	nginx := baseNginx()
+	nginx.Spec.Config = &v1alpha1.ConfigRef{
+		Kind:  v1alpha1.ConfigKindTemplate,
+		Value: "server { listen {{ .Values.port }}; }",
+	}
+
+	_, err := NewDeployment(&nginx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to render config template")
+}
+
+func TestNewDaemonSet(t *testing.T) {
+	nginx := baseNginx()
+	nginx.Spec.DeploymentMode = v1alpha1.DeploymentModeDaemonSet
+
+	deploy, err := NewDeployment(&nginx)
+	require.NoError(t, err)
+
+	ds, err := NewDaemonSet(&nginx)
+	require.NoError(t, err)
+	assert.Equal(t, metav1.TypeMeta{Kind: "DaemonSet", APIVersion: "apps/v1"}, ds.TypeMeta)
+	assert.Equal(t, deploy.ObjectMeta, ds.ObjectMeta)
+	assert.Equal(t, deploy.Spec.Selector, ds.Spec.Selector)
+	assert.Equal(t, deploy.Spec.Template, ds.Spec.Template)
+}
+
+func TestNewInternalService(t *testing.T) {
+	nginx := baseNginx()
+	nginx.Spec.Metrics = &v1alpha1.NginxMetrics{Enabled: true}
+	nginx.Spec.Service = &v1alpha1.NginxService{Type: corev1.ServiceTypeLoadBalancer}
+	nginx.Spec.InternalService = &v1alpha1.NginxService{
+		Annotations: map[string]string{"networking.gke.io/load-balancer-type": "Internal"},
+	}
+
+	svc := NewInternalService(&nginx)
+	assert.Equal(t, "my-nginx-internal", svc.Name)
+	assert.Equal(t, "default", svc.Namespace)
+	assert.Equal(t, corev1.ServiceTypeClusterIP, svc.Spec.Type)
+	assert.Equal(t, map[string]string{"networking.gke.io/load-balancer-type": "Internal"}, svc.Annotations)
+	assert.Equal(t, LabelsForNginx("my-nginx"), svc.Spec.Selector)
+	assert.Equal(t, []corev1.ServicePort{
+		{Name: "http", Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromString("http"), Port: int32(80)},
+		{Name: "https", Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromString("https"), Port: int32(443)},
+	}, svc.Spec.Ports)
+}
+
 func TestExtractNginxSpec(t *testing.T) {
 	mustMarshal := func(t *testing.T, n v1alpha1.NginxSpec) string {
 		data, err := json.Marshal(n)
@@ -1759,6 +2616,92 @@ func TestNewIngress(t *testing.T) {
 			},
 		},
 
+		"with custom hosts and path": {
+			nginx: func() v1alpha1.Nginx {
+				n := baseNginx()
+				n.Spec.Ingress = &v1alpha1.NginxIngress{
+					Hosts: []string{"www.example.com", "static.example.com"},
+					Path:  "/static",
+				}
+				n.Spec.TLS = []v1alpha1.NginxTLS{
+					{SecretName: "www-cert", Hosts: []string{"www.example.com"}},
+				}
+				return n
+			},
+			want: &networkingv1.Ingress{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "networking.k8s.io/v1",
+					Kind:       "Ingress",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-nginx",
+					Namespace: "default",
+					Labels: map[string]string{
+						"nginx.tsuru.io/app":           "nginx",
+						"nginx.tsuru.io/resource-name": "my-nginx",
+					},
+					OwnerReferences: []metav1.OwnerReference{
+						*metav1.NewControllerRef(&nginx, schema.GroupVersionKind{
+							Group:   v1alpha1.GroupVersion.Group,
+							Version: v1alpha1.GroupVersion.Version,
+							Kind:    "Nginx",
+						}),
+					},
+				},
+				Spec: networkingv1.IngressSpec{
+					DefaultBackend: &networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: "my-nginx-service",
+							Port: networkingv1.ServiceBackendPort{Name: "http"},
+						},
+					},
+					Rules: []networkingv1.IngressRule{
+						{
+							Host: "www.example.com",
+							IngressRuleValue: networkingv1.IngressRuleValue{
+								HTTP: &networkingv1.HTTPIngressRuleValue{
+									Paths: []networkingv1.HTTPIngressPath{
+										{
+											Path:     "/static",
+											PathType: func(pt networkingv1.PathType) *networkingv1.PathType { return &pt }(networkingv1.PathTypePrefix),
+											Backend: networkingv1.IngressBackend{
+												Service: &networkingv1.IngressServiceBackend{
+													Name: "my-nginx-service",
+													Port: networkingv1.ServiceBackendPort{Name: "http"},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+						{
+							Host: "static.example.com",
+							IngressRuleValue: networkingv1.IngressRuleValue{
+								HTTP: &networkingv1.HTTPIngressRuleValue{
+									Paths: []networkingv1.HTTPIngressPath{
+										{
+											Path:     "/static",
+											PathType: func(pt networkingv1.PathType) *networkingv1.PathType { return &pt }(networkingv1.PathTypePrefix),
+											Backend: networkingv1.IngressBackend{
+												Service: &networkingv1.IngressServiceBackend{
+													Name: "my-nginx-service",
+													Port: networkingv1.ServiceBackendPort{Name: "http"},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					TLS: []networkingv1.IngressTLS{
+						{SecretName: "www-cert", Hosts: []string{"www.example.com"}},
+					},
+				},
+			},
+		},
+
 		"when there are TLS configs on the Nginx, should create the ingress with TLS configs too": {
 			nginx: func() v1alpha1.Nginx {
 				n := baseNginx()
@@ -1906,3 +2849,292 @@ func TestNewIngress(t *testing.T) {
 		})
 	}
 }
+
+func TestNewHTTPRoute(t *testing.T) {
+	nginx := baseNginx()
+	nginx.Spec.Gateway = &v1alpha1.NginxGateway{
+		ParentRefs: []v1alpha1.NginxGatewayParentRef{
+			{Name: "public"},
+			{Name: "internal", Namespace: "gateways", SectionName: "https"},
+		},
+		Hostnames:   []string{"www.example.com"},
+		Annotations: map[string]string{"custom.annotations.example.com/key": "value"},
+		Labels:      map[string]string{"custom.labels.example.com/key": "value"},
+	}
+
+	route := NewHTTPRoute(&nginx)
+	assert.Equal(t, HTTPRouteGroupVersionKind, route.GroupVersionKind())
+	assert.Equal(t, "my-nginx", route.GetName())
+	assert.Equal(t, "default", route.GetNamespace())
+	assert.Equal(t, map[string]string{
+		"nginx.tsuru.io/app":            "nginx",
+		"nginx.tsuru.io/resource-name":  "my-nginx",
+		"custom.labels.example.com/key": "value",
+	}, route.GetLabels())
+	assert.Equal(t, map[string]string{"custom.annotations.example.com/key": "value"}, route.GetAnnotations())
+	assert.Equal(t, []metav1.OwnerReference{
+		*metav1.NewControllerRef(&nginx, schema.GroupVersionKind{
+			Group:   v1alpha1.GroupVersion.Group,
+			Version: v1alpha1.GroupVersion.Version,
+			Kind:    "Nginx",
+		}),
+	}, route.GetOwnerReferences())
+	assert.Equal(t, map[string]interface{}{
+		"parentRefs": []interface{}{
+			map[string]interface{}{"group": "gateway.networking.k8s.io", "kind": "Gateway", "name": "public"},
+			map[string]interface{}{"group": "gateway.networking.k8s.io", "kind": "Gateway", "name": "internal", "namespace": "gateways", "sectionName": "https"},
+		},
+		"hostnames": []interface{}{"www.example.com"},
+		"rules": []interface{}{
+			map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{
+						"path": map[string]interface{}{"type": "PathPrefix", "value": "/"},
+					},
+				},
+				"backendRefs": []interface{}{
+					map[string]interface{}{"group": "", "kind": "Service", "name": "my-nginx-service", "port": int64(80), "weight": int64(1)},
+				},
+			},
+		},
+	}, route.Object["spec"])
+
+	nginx.Spec.Service = &v1alpha1.NginxService{
+		Ports: []v1alpha1.NginxServicePort{{Name: "http", Port: 8080}},
+	}
+	route = NewHTTPRoute(&nginx)
+	rules := route.Object["spec"].(map[string]interface{})["rules"].([]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"group": "", "kind": "Service", "name": "my-nginx-service", "port": int64(8080), "weight": int64(1)},
+	}, rules[0].(map[string]interface{})["backendRefs"])
+}
+
+func TestNewServiceMonitor(t *testing.T) {
+	nginx := baseNginx()
+	nginx.Spec.Metrics = &v1alpha1.NginxMetrics{
+		Enabled: true,
+		ServiceMonitor: &v1alpha1.NginxServiceMonitor{
+			Interval: "30s",
+			Labels:   map[string]string{"release": "prometheus"},
+		},
+	}
+
+	sm := NewServiceMonitor(&nginx)
+	assert.Equal(t, ServiceMonitorGroupVersionKind, sm.GroupVersionKind())
+	assert.Equal(t, "my-nginx", sm.GetName())
+	assert.Equal(t, "default", sm.GetNamespace())
+	assert.Equal(t, map[string]string{
+		"nginx.tsuru.io/app":           "nginx",
+		"nginx.tsuru.io/resource-name": "my-nginx",
+		"release":                      "prometheus",
+	}, sm.GetLabels())
+	assert.Equal(t, []metav1.OwnerReference{
+		*metav1.NewControllerRef(&nginx, schema.GroupVersionKind{
+			Group:   v1alpha1.GroupVersion.Group,
+			Version: v1alpha1.GroupVersion.Version,
+			Kind:    "Nginx",
+		}),
+	}, sm.GetOwnerReferences())
+	assert.Equal(t, map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				"nginx.tsuru.io/app":           "nginx",
+				"nginx.tsuru.io/resource-name": "my-nginx",
+			},
+		},
+		"endpoints": []interface{}{
+			map[string]interface{}{"port": "metrics", "interval": "30s"},
+		},
+	}, sm.Object["spec"])
+	assert.Equal(t, map[string]string{"release": "prometheus"}, nginx.Spec.Metrics.ServiceMonitor.Labels)
+}
+
+func TestNewHorizontalPodAutoscaler(t *testing.T) {
+	tests := map[string]struct {
+		autoscaling *v1alpha1.NginxAutoscaling
+		want        autoscalingv2.HorizontalPodAutoscalerSpec
+	}{
+		"with default values": {
+			autoscaling: &v1alpha1.NginxAutoscaling{MaxReplicas: 10},
+			want: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "my-nginx"},
+				MinReplicas:    func(n int32) *int32 { return &n }(1),
+				MaxReplicas:    10,
+				Metrics: []autoscalingv2.MetricSpec{
+					{
+						Type: autoscalingv2.ResourceMetricSourceType,
+						Resource: &autoscalingv2.ResourceMetricSource{
+							Name: corev1.ResourceCPU,
+							Target: autoscalingv2.MetricTarget{
+								Type:               autoscalingv2.UtilizationMetricType,
+								AverageUtilization: func(n int32) *int32 { return &n }(80),
+							},
+						},
+					},
+				},
+			},
+		},
+		"with memory target only": {
+			autoscaling: &v1alpha1.NginxAutoscaling{
+				MinReplicas:                       func(n int32) *int32 { return &n }(2),
+				MaxReplicas:                       5,
+				TargetMemoryUtilizationPercentage: func(n int32) *int32 { return &n }(70),
+			},
+			want: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "my-nginx"},
+				MinReplicas:    func(n int32) *int32 { return &n }(2),
+				MaxReplicas:    5,
+				Metrics: []autoscalingv2.MetricSpec{
+					{
+						Type: autoscalingv2.ResourceMetricSourceType,
+						Resource: &autoscalingv2.ResourceMetricSource{
+							Name: corev1.ResourceMemory,
+							Target: autoscalingv2.MetricTarget{
+								Type:               autoscalingv2.UtilizationMetricType,
+								AverageUtilization: func(n int32) *int32 { return &n }(70),
+							},
+						},
+					},
+				},
+			},
+		},
+		"with CPU and memory targets": {
+			autoscaling: &v1alpha1.NginxAutoscaling{
+				MaxReplicas:                       5,
+				TargetCPUUtilizationPercentage:    func(n int32) *int32 { return &n }(60),
+				TargetMemoryUtilizationPercentage: func(n int32) *int32 { return &n }(70),
+			},
+			want: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "my-nginx"},
+				MinReplicas:    func(n int32) *int32 { return &n }(1),
+				MaxReplicas:    5,
+				Metrics: []autoscalingv2.MetricSpec{
+					{
+						Type: autoscalingv2.ResourceMetricSourceType,
+						Resource: &autoscalingv2.ResourceMetricSource{
+							Name: corev1.ResourceCPU,
+							Target: autoscalingv2.MetricTarget{
+								Type:               autoscalingv2.UtilizationMetricType,
+								AverageUtilization: func(n int32) *int32 { return &n }(60),
+							},
+						},
+					},
+					{
+						Type: autoscalingv2.ResourceMetricSourceType,
+						Resource: &autoscalingv2.ResourceMetricSource{
+							Name: corev1.ResourceMemory,
+							Target: autoscalingv2.MetricTarget{
+								Type:               autoscalingv2.UtilizationMetricType,
+								AverageUtilization: func(n int32) *int32 { return &n }(70),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			nginx := baseNginx()
+			nginx.Spec.Autoscaling = tt.autoscaling
+
+			hpa := NewHorizontalPodAutoscaler(&nginx)
+			assert.Equal(t, "my-nginx", hpa.Name)
+			assert.Equal(t, "default", hpa.Namespace)
+			assert.Equal(t, LabelsForNginx("my-nginx"), hpa.Labels)
+			assert.Equal(t, tt.want, hpa.Spec)
+		})
+	}
+}
+
+func TestNewPodDisruptionBudget(t *testing.T) {
+	nginx := baseNginx()
+	nginx.Spec.PodDisruptionBudget = &v1alpha1.NginxPodDisruptionBudget{
+		MaxUnavailable: func(v intstr.IntOrString) *intstr.IntOrString { return &v }(intstr.FromString("25%")),
+	}
+
+	pdb := NewPodDisruptionBudget(&nginx)
+	assert.Equal(t, "my-nginx", pdb.Name)
+	assert.Equal(t, "default", pdb.Namespace)
+	assert.Equal(t, LabelsForNginx("my-nginx"), pdb.Labels)
+	assert.Equal(t, policyv1.PodDisruptionBudgetSpec{
+		Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"nginx.tsuru.io/app":           "nginx",
+				"nginx.tsuru.io/resource-name": "my-nginx",
+			},
+		},
+		MaxUnavailable: func(v intstr.IntOrString) *intstr.IntOrString { return &v }(intstr.FromString("25%")),
+	}, pdb.Spec)
+}
+
+func TestNewNetworkPolicy(t *testing.T) {
+	nginx := baseNginx()
+	nginx.Spec.Metrics = &v1alpha1.NginxMetrics{Enabled: true}
+	nginx.Spec.NetworkPolicy = &v1alpha1.NginxNetworkPolicy{
+		From: []networkingv1.NetworkPolicyPeer{
+			{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "ingress"}}},
+		},
+		Egress: []networkingv1.NetworkPolicyEgressRule{
+			{To: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}}}},
+		},
+	}
+
+	port := func(protocol corev1.Protocol, port int) networkingv1.NetworkPolicyPort {
+		p := intstr.FromInt(port)
+		return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &p}
+	}
+
+	np := NewNetworkPolicy(&nginx)
+	assert.Equal(t, "my-nginx", np.Name)
+	assert.Equal(t, "default", np.Namespace)
+	assert.Equal(t, LabelsForNginx("my-nginx"), np.Labels)
+	assert.Equal(t, networkingv1.NetworkPolicySpec{
+		PodSelector: metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"nginx.tsuru.io/app":           "nginx",
+				"nginx.tsuru.io/resource-name": "my-nginx",
+			},
+		},
+		Ingress: []networkingv1.NetworkPolicyIngressRule{
+			{
+				Ports: []networkingv1.NetworkPolicyPort{
+					port(corev1.ProtocolTCP, 8080),
+					port(corev1.ProtocolTCP, 8443),
+					port(corev1.ProtocolTCP, 9113),
+				},
+				From: []networkingv1.NetworkPolicyPeer{
+					{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "ingress"}}},
+				},
+			},
+		},
+		Egress: []networkingv1.NetworkPolicyEgressRule{
+			{Ports: []networkingv1.NetworkPolicyPort{port(corev1.ProtocolUDP, 53), port(corev1.ProtocolTCP, 53)}},
+			{To: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}}}},
+		},
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+	}, np.Spec)
+	assert.Empty(t, nginx.Spec.PodTemplate.Ports)
+}
+
+func TestNewServiceAccount(t *testing.T) {
+	nginx := baseNginx()
+	nginx.Spec.ServiceAccount = &v1alpha1.NginxServiceAccount{
+		Annotations:      map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::111122223333:role/my-role"},
+		Labels:           map[string]string{"team": "edge", "nginx.tsuru.io/app": "other"},
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry-credentials"}},
+	}
+
+	sa := NewServiceAccount(&nginx)
+	assert.Equal(t, "my-nginx", sa.Name)
+	assert.Equal(t, "default", sa.Namespace)
+	assert.Equal(t, map[string]string{
+		"nginx.tsuru.io/app":           "nginx",
+		"nginx.tsuru.io/resource-name": "my-nginx",
+		"team":                         "edge",
+	}, sa.Labels)
+	assert.Equal(t, map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::111122223333:role/my-role"}, sa.Annotations)
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "registry-credentials"}}, sa.ImagePullSecrets)
+	assert.Equal(t, map[string]string{"team": "edge", "nginx.tsuru.io/app": "other"}, nginx.Spec.ServiceAccount.Labels)
+}